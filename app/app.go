@@ -1,20 +1,20 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
-	"github.com/meta-node-blockchain/meta-node/cmd/client"
 	"github.com/meta-node-blockchain/meta-node/pkg/logger"
 	// "github.com/meta-node-blockchain/meta-node/types"
+	"github.com/meta-node-blockchain/cardvisa/internal/api"
 	"github.com/meta-node-blockchain/cardvisa/internal/network"
 	"github.com/meta-node-blockchain/cardvisa/internal/config"
 		"github.com/meta-node-blockchain/cardvisa/internal/services"
-	c_config "github.com/meta-node-blockchain/meta-node/cmd/client/pkg/config"
 	"github.com/meta-node-blockchain/cardvisa/internal/database"
 	"github.com/meta-node-blockchain/cardvisa/internal/model"
 
@@ -23,12 +23,18 @@ import (
 type App struct {
 	Config *config.AppConfig
 	ApiApp *gin.Engine
+	ApiServer *api.Server
 
-	ChainClient *client.Client
+	// ChainBackend theo ChainId khi các deployment nằm trên nhiều chain
+	Backends map[uint64]services.ChainBackend
+	// TxManager theo ChainId, tuần tự hoá transaction của sender cho mọi deployment
+	TxManagers map[uint64]*services.TxManager
+	Store       database.Store
 	EventChan   chan model.EventLog
 	StopChan    chan bool
 
 	CardHandler *network.CardHandler
+	Deployments *network.Deployments
 	// StorageClient *client.Client
 }
 
@@ -47,22 +53,9 @@ func NewApp(
 		log.Fatal("invalid configuration", err)
 		return nil, err
 	}
-	app := &App{}
-	app.ChainClient, err = client.NewClient(
-		&c_config.ClientConfig{
-			Version_:                config.MetaNodeVersion,
-			PrivateKey_:             config.PrivateKey_,
-			ParentAddress:           config.AdminAddress,
-			ParentConnectionAddress: config.ParentConnectionAddress,
-			// DnsLink_:                config.DnsLink(),
-			ConnectionAddress_:      config.ConnectionAddress_,
-			ParentConnectionType:    config.ParentConnectionType,
-			ChainId:                 config.ChainId,
-		},
-	)
-	if err != nil {
-		logger.Error(fmt.Sprintf("error when create chain client %v", err))
-		return nil, err
+	app := &App{
+		Backends:   make(map[uint64]services.ChainBackend),
+		TxManagers: make(map[uint64]*services.TxManager),
 	}
 	// app.StorageClient, err = client.NewClient(
 	// 	&c_config.ClientConfig{
@@ -90,17 +83,9 @@ func NewApp(
 	// 	return nil, err
 	// }
 	app.EventChan = make(chan model.EventLog, 1000) // buffer 100 để tránh nghẽn
-	leveldb, err :=database.Open(config.PathLevelDB)
-	readerHub, err := os.Open(config.CardABIPath)
-	if err != nil {
-		logger.Error("Error occured while read create card smart contract abi")
-		return nil, err
-	}
-	defer readerHub.Close()
-
-	cardAbi, err := abi.JSON(readerHub)
+	app.Store, err = OpenStore(config)
 	if err != nil {
-		logger.Error("Error occured while parse create card smart contract abi")
+		logger.Error("Can not open store:", err)
 		return nil, err
 	}
 
@@ -114,23 +99,50 @@ func NewApp(
 		logger.Error("Can not read private key pem file")
 		return nil, err
 	}
-	servs := services.NewSendTransactionService(
-		app.ChainClient,
-		&cardAbi,
-		common.HexToAddress(config.CardAddress),
-		common.HexToAddress(config.AdminAddress),
-	)
-
-	app.CardHandler = network.NewCardEventHandler(
-		config,
-		servs,
-		&cardAbi,
-		string(bserverPrivateKey),
-		leveldb,
-		config.ThirdPartyApiUrl,
-		string(bserverPublicKey),
-		app.EventChan,
-	)
+	handlers := []*network.CardHandler{}
+	for _, contract := range config.Contracts() {
+		txManager, err := app.txManager(config, contract)
+		if err != nil {
+			return nil, err
+		}
+		cardAbi, err := loadCardABI(contract.ABIPath)
+		if err != nil {
+			return nil, err
+		}
+		servs := services.NewSendTransactionService(
+			txManager,
+			&cardAbi,
+			common.HexToAddress(contract.Address),
+			config.Transactions,
+			newGasEstimator(contract),
+		)
+		if config.StatusBatch.Enabled && statusBatchSupported(contract, &cardAbi, txManager.Sender()) {
+			servs = services.NewStatusBatcher(servs, &cardAbi, config.StatusBatch.Window, config.StatusBatch.MaxSize)
+		}
+
+		query, err := newContractQuery(config, contract, servs, &cardAbi, txManager.Sender())
+		if err != nil {
+			return nil, err
+		}
+
+		handlers = append(handlers, network.NewCardEventHandler(
+			config,
+			contract,
+			servs,
+			query,
+			&cardAbi,
+			string(bserverPrivateKey),
+			app.Store,
+			config.ThirdPartyApiUrl,
+			string(bserverPublicKey),
+			app.EventChan,
+		))
+	}
+	app.Deployments = network.NewDeployments(handlers...)
+	app.CardHandler = app.Deployments.Primary()
+
+	app.ApiApp = gin.New()
+	app.ApiServer = api.NewServer(config, app.CardHandler, app.ApiApp)
 
 	app.Config = config
 	return app, nil
@@ -138,14 +150,26 @@ func NewApp(
 
 func (app *App) Run() {
 	app.StopChan = make(chan bool)
-	if app.CardHandler != nil {
-		app.CardHandler.VerifyPublicKey()
-		// app.CardHandler.GetPoolInfo()
-	} else {
+	if app.CardHandler == nil {
 		logger.Error("CardHandler is nil. Cannot verify public key")
 		return
 	}
-	go app.CardHandler.ListenEvents() // BẮT ĐẦU LẮNG NGHE EVENT
+	for _, handler := range app.Deployments.All() {
+		handler.VerifyPublicKey()
+		// app.CardHandler.GetPoolInfo()
+		go handler.ListenEvents() // BẮT ĐẦU LẮNG NGHE EVENT
+		go handler.RunCleanUsageScheduler()
+	}
+	// Các job này chạy một lần cho cả service, tự chuyển từng charge về deployment sở hữu
+	go app.CardHandler.ApplyHeldDecisions()
+	go app.CardHandler.ResumeSettlements()
+	go app.CardHandler.RunReconcileScheduler()
+	go func() {
+		logger.Info("API server listening on", app.Config.API_PORT)
+		if err := app.ApiServer.Start(); err != nil {
+			logger.Error("API server stopped:", err)
+		}
+	}()
 	for {
 		select {
 		case <-app.StopChan:
@@ -153,14 +177,28 @@ func (app *App) Run() {
 		case eventLogs := <-app.EventChan:
 			fmt.Println("📩 Event Received:", eventLogs)
 			// logger.Debug("📩 Event Received:", eventLogs)
-			app.CardHandler.HandleConnectSmartContract(eventLogs)
+			app.Deployments.Dispatch(eventLogs)
 			
 		}
 	}
 }
 
 func (app *App) Stop() error {
-	app.ChainClient.Close()
+	if app.ApiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.ApiServer.Shutdown(ctx); err != nil {
+			logger.Error("API server shutdown:", err)
+		}
+	}
+	for _, backend := range app.Backends {
+		backend.Close()
+	}
+	if app.Store != nil {
+		if err := app.Store.Close(); err != nil {
+			logger.Error("Store close:", err)
+		}
+	}
 
 	logger.Warn("App Stopped")
 	return nil
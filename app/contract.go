@@ -0,0 +1,200 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	"github.com/meta-node-blockchain/meta-node/cmd/client"
+	c_config "github.com/meta-node-blockchain/meta-node/cmd/client/pkg/config"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// chainBackend dùng lại backend đã tạo cho chainId, các deployment cùng chain dùng chung
+// một backend (và một sender)
+func (app *App) chainBackend(config *config.AppConfig, contract config.CardContractConfig) (services.ChainBackend, error) {
+	if backend, ok := app.Backends[contract.ChainId]; ok {
+		return backend, nil
+	}
+	backend, err := newChainBackend(config, contract)
+	if err != nil {
+		return nil, err
+	}
+	app.Backends[contract.ChainId] = backend
+	return backend, nil
+}
+
+// txManager trả về TxManager dùng chung của sender trên chain của contract
+func (app *App) txManager(config *config.AppConfig, contract config.CardContractConfig) (*services.TxManager, error) {
+	if txManager, ok := app.TxManagers[contract.ChainId]; ok {
+		return txManager, nil
+	}
+	backend, err := app.chainBackend(config, contract)
+	if err != nil {
+		return nil, err
+	}
+	txManager := services.NewTxManager(backend)
+	app.TxManagers[contract.ChainId] = txManager
+	return txManager, nil
+}
+
+// newChainBackend tạo backend theo ChainBackend: meta-node ký bằng AdminAddress, ethereum
+// gửi qua RpcURL của contract và ký bằng EthPrivateKey
+func newChainBackend(config *config.AppConfig, contract config.CardContractConfig) (services.ChainBackend, error) {
+	if config.EthereumBackend() {
+		backend, err := services.NewEthBackend(contract.RpcURL, config.EthPrivateKey)
+		if err != nil {
+			logger.Error("error when create ethereum backend", err)
+			return nil, err
+		}
+		return backend, nil
+	}
+	chainClient, err := newChainClient(config, contract.ChainId)
+	if err != nil {
+		return nil, err
+	}
+	return services.NewMetaNodeBackend(chainClient, common.HexToAddress(config.AdminAddress)), nil
+}
+
+func newChainClient(config *config.AppConfig, chainId uint64) (*client.Client, error) {
+	chainClient, err := client.NewClient(
+		&c_config.ClientConfig{
+			Version_:                config.MetaNodeVersion,
+			PrivateKey_:             config.PrivateKey_,
+			ParentAddress:           config.AdminAddress,
+			ParentConnectionAddress: config.ParentConnectionAddress,
+			// DnsLink_:                config.DnsLink(),
+			ConnectionAddress_:   config.ConnectionAddress_,
+			ParentConnectionType: config.ParentConnectionType,
+			ChainId:              chainId,
+		},
+	)
+	if err != nil {
+		logger.Error(fmt.Sprintf("error when create chain client %v", err))
+		return nil, err
+	}
+	return chainClient, nil
+}
+
+func loadCardABI(path string) (abi.ABI, error) {
+	readerHub, err := os.Open(path)
+	if err != nil {
+		logger.Error("Error occured while read create card smart contract abi")
+		return abi.ABI{}, err
+	}
+	defer readerHub.Close()
+
+	cardAbi, err := abi.JSON(readerHub)
+	if err != nil {
+		logger.Error("Error occured while parse create card smart contract abi")
+		return abi.ABI{}, err
+	}
+	return cardAbi, nil
+}
+
+// newContractQuery đọc contract bằng eth_call qua RpcURL của deployment; chưa cấu hình RpcURL
+// thì quay về đường gửi transaction qua ChainBackend như trước
+func newContractQuery(
+	config *config.AppConfig,
+	contract config.CardContractConfig,
+	servs services.SendTransactionService,
+	cardAbi *abi.ABI,
+	from common.Address,
+) (services.ContractQuery, error) {
+	if contract.RpcURL == "" {
+		logger.Warn("RpcURL chưa cấu hình, đọc contract bằng transaction:", contract.Name)
+		return services.NewTxQuery(servs, cardAbi), nil
+	}
+	query, err := services.NewEthCallQuery(
+		contract.RpcURL,
+		cardAbi,
+		common.HexToAddress(contract.Address),
+		from,
+	)
+	if err != nil {
+		logger.Error("error when dial RpcURL", err)
+		return nil, err
+	}
+	return query, nil
+}
+
+// statusBatchSupported cho biết contract đã deploy có batchUpdateTxStatus; không có RpcURL
+// để probe thì tin cấu hình StatusBatch của deployment
+func statusBatchSupported(
+	contract config.CardContractConfig,
+	cardAbi *abi.ABI,
+	from common.Address,
+) bool {
+	if contract.RpcURL == "" {
+		if !contract.StatusBatch {
+			logger.Warn("StatusBatch: không có RpcURL để probe và deployment chưa bật StatusBatch, gửi từng lời gọi:", contract.Name)
+		}
+		return contract.StatusBatch
+	}
+	ok, err := services.ProbeStatusBatch(contract.RpcURL, cardAbi, common.HexToAddress(contract.Address), from)
+	if err != nil {
+		logger.Error("error when probe batchUpdateTxStatus", err)
+		return false
+	}
+	if !ok {
+		logger.Warn("StatusBatch: contract đã deploy không có batchUpdateTxStatus, gửi từng lời gọi:", contract.Name)
+	}
+	return ok
+}
+
+// newGasEstimator trả về nil khi deployment không có RpcURL, service sẽ dùng MaxGas
+func newGasEstimator(contract config.CardContractConfig) services.GasEstimator {
+	if contract.RpcURL == "" {
+		return nil
+	}
+	estimator, err := services.NewGasEstimator(contract.RpcURL)
+	if err != nil {
+		logger.Warn("không tạo được gas estimator, dùng MaxGas:", err)
+		return nil
+	}
+	return estimator
+}
+
+// NewContractService chỉ tạo chain client và service gửi transaction tới một deployment của
+// card contract (tên rỗng là deployment đầu tiên), dùng cho các lệnh quản trị không cần mở
+// LevelDB. Gọi close() khi dùng xong.
+func NewContractService(configPath string, name string) (services.SendTransactionService, func(), error) {
+	config, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	contracts := config.Contracts()
+	contract := contracts[0]
+	if name != "" {
+		found := false
+		for _, c := range contracts {
+			if c.Name == name {
+				contract, found = c, true
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("unknown contract %q", name)
+		}
+	}
+	backend, err := newChainBackend(config, contract)
+	if err != nil {
+		return nil, nil, err
+	}
+	cardAbi, err := loadCardABI(contract.ABIPath)
+	if err != nil {
+		backend.Close()
+		return nil, nil, err
+	}
+	servs := services.NewSendTransactionService(
+		services.NewTxManager(backend),
+		&cardAbi,
+		common.HexToAddress(contract.Address),
+		config.Transactions,
+		newGasEstimator(contract),
+	)
+	return servs, backend.Close, nil
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+)
+
+// OpenStore mở Store theo Storage.Backend; LevelDB và Badger chỉ cho phép một process mở
+func OpenStore(cfg *config.AppConfig) (database.Store, error) {
+	return OpenStoreAt(cfg, StoreDir(cfg))
+}
+
+// StoreDir trả về thư mục dữ liệu của backend LevelDB/Badger, rỗng khi backend là SQL
+func StoreDir(cfg *config.AppConfig) string {
+	switch cfg.Storage.Backend {
+	case "", config.StorageLevelDB:
+		return cfg.PathLevelDB
+	case config.StorageBadger:
+		return cfg.Storage.Path
+	}
+	return ""
+}
+
+// OpenStoreAt mở Store theo Storage.Backend tại dir thay cho thư mục cấu hình; dir bị bỏ qua
+// với backend SQL
+func OpenStoreAt(cfg *config.AppConfig, dir string) (database.Store, error) {
+	var kv database.KV
+	var err error
+	switch cfg.Storage.Backend {
+	case "", config.StorageLevelDB:
+		kv, err = database.OpenLevelDB(dir)
+	case config.StorageBadger:
+		kv, err = database.OpenBadger(dir)
+	case config.StorageSQL:
+		kv, err = database.OpenSQL(cfg.Storage.Driver, cfg.Storage.DSN)
+	default:
+		return nil, fmt.Errorf("unknown Storage.Backend %q", cfg.Storage.Backend)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return database.NewStore(kv), nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+)
+
+const adminUsage = `usage: cardvisa admin <command> [flags]
+
+  merchant-rule   -merchant <addr> [-regions VN,US] [-per-minute n] [-per-hour n] [-per-day n] [-per-week n]
+  global-rule     [-per-minute n] [-per-hour n] [-per-day n] [-per-week n] [-total n]
+  card-lock       -card-hash <hex> [-locked=<bool>]
+  token-active    -token <hex> [-active=<bool>]
+  lock            [-locked=<bool>]
+  set-admin       -address <addr> [-enabled=<bool>]
+  set-processor   -address <addr>
+  backend-pubkey  [-pubkey <hex> | -file <path>]   (mặc định đọc StoredPubKey trong config)
+  clean-usage     -before <unix timestamp>
+
+Mọi lệnh nhận thêm -contract <Name> để chọn deployment trong CardContracts.
+Lệnh gửi transaction trực tiếp tới card contract bằng PrivateKey_ trong config,
+không ghi lock audit vào LevelDB như API admin.`
+
+func runAdminCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+	fs := flag.NewFlagSet("admin "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	contract := fs.String("contract", "", "CardContracts name, default is the first deployment")
+	merchant := fs.String("merchant", "", "Merchant address")
+	regions := fs.String("regions", "", "Comma separated allowed regions")
+	perMinute := fs.Uint64("per-minute", 0, "Max charges per minute")
+	perHour := fs.Uint64("per-hour", 0, "Max charges per hour")
+	perDay := fs.Uint64("per-day", 0, "Max charges per day")
+	perWeek := fs.Uint64("per-week", 0, "Max charges per week")
+	total := fs.Uint64("total", 0, "Max total charges")
+	cardHash := fs.String("card-hash", "", "Card hash (bytes32 hex)")
+	token := fs.String("token", "", "Token id (bytes32 hex)")
+	locked := fs.Bool("locked", true, "Lock state")
+	active := fs.Bool("active", true, "Token active state")
+	address := fs.String("address", "", "Account address")
+	enabled := fs.Bool("enabled", true, "Grant or revoke admin")
+	pubKey := fs.String("pubkey", "", "Backend public key (hex)")
+	file := fs.String("file", "", "Backend public key file")
+	before := fs.Uint64("before", 0, "Clean usage recorded before this unix timestamp")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	servs, closeClient, err := app.NewContractService(*configPath, *contract)
+	if err != nil {
+		return err
+	}
+	defer closeClient()
+
+	var result *services.TxResult
+	switch args[0] {
+	case "merchant-rule":
+		if !common.IsHexAddress(*merchant) {
+			return fmt.Errorf("invalid -merchant %q", *merchant)
+		}
+		rule := model.MerchantRule{
+			Merchant:     common.HexToAddress(*merchant).Hex(),
+			MaxPerMinute: *perMinute,
+			MaxPerHour:   *perHour,
+			MaxPerDay:    *perDay,
+			MaxPerWeek:   *perWeek,
+		}
+		if *regions != "" {
+			rule.AllowedRegions = strings.Split(*regions, ",")
+		}
+		result, err = servs.SetMerchantRule(rule)
+	case "global-rule":
+		result, err = servs.SetGlobalRule(model.GlobalRule{
+			MaxPerMinute: *perMinute,
+			MaxPerHour:   *perHour,
+			MaxPerDay:    *perDay,
+			MaxPerWeek:   *perWeek,
+			MaxTotal:     *total,
+		})
+	case "card-lock":
+		var hash [32]byte
+		if hash, err = parseBytes32("-card-hash", *cardHash); err != nil {
+			return err
+		}
+		result, err = servs.SetCardLocked(hash, *locked)
+	case "token-active":
+		var tokenId [32]byte
+		if tokenId, err = parseBytes32("-token", *token); err != nil {
+			return err
+		}
+		result, err = servs.SetTokenActive(tokenId, *active)
+	case "lock":
+		result, err = servs.SetLock(*locked)
+	case "set-admin":
+		if !common.IsHexAddress(*address) {
+			return fmt.Errorf("invalid -address %q", *address)
+		}
+		result, err = servs.SetAdmin(common.HexToAddress(*address), *enabled)
+	case "set-processor":
+		if !common.IsHexAddress(*address) {
+			return fmt.Errorf("invalid -address %q", *address)
+		}
+		result, err = servs.SetProcessor(common.HexToAddress(*address))
+	case "backend-pubkey":
+		var key []byte
+		if key, err = backendPubKey(*configPath, *pubKey, *file); err != nil {
+			return err
+		}
+		result, err = servs.SetBackendPubKey(key)
+	case "clean-usage":
+		if *before == 0 {
+			return errors.New("-before is required")
+		}
+		result, err = servs.CleanUsage(*before)
+	default:
+		return errors.New(adminUsage)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(map[string]interface{}{"success": true, "result": result})
+}
+
+func parseBytes32(name string, v string) ([32]byte, error) {
+	var out [32]byte
+	b := common.FromHex(v)
+	if len(b) != 32 {
+		return out, fmt.Errorf("invalid %s %q", name, v)
+	}
+	copy(out[:], b)
+	return out, nil
+}
+
+// backendPubKey lấy public key dạng hex từ -pubkey, -file hoặc StoredPubKey trong config theo thứ tự đó,
+// cùng định dạng mà VerifyPublicKey so sánh khi service khởi động
+func backendPubKey(configPath string, pubKey string, file string) ([]byte, error) {
+	if pubKey == "" {
+		if file == "" {
+			cfg, err := config.LoadConfig(configPath)
+			if err != nil {
+				return nil, err
+			}
+			file = cfg.StoredPubKey
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		pubKey = strings.TrimSpace(string(data))
+	}
+	key, err := hex.DecodeString(strings.TrimPrefix(pubKey, "0x"))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid backend public key %q", pubKey)
+	}
+	return key, nil
+}
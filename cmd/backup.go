@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/backup"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+)
+
+const backupUsage = `usage:
+  cardvisa backup  [-config <path>] [-out <file>] [-key-file <file>]
+  cardvisa restore [-config <path>] -in <file> [-key-file <file>] [-force] [-verify]
+
+backup ghi snapshot mã hoá của store (token, cursor, ledger charge) ra -out, mặc định là một
+file mới trong Backup.Dir. restore kiểm tra checksum toàn bộ file trước khi ghi; store đích phải
+rỗng trừ khi có -force, -verify chỉ kiểm tra file mà không ghi. -key-file mặc định là Backup.KeyFile.
+
+-force thay dữ liệu hiện có mà không để lại store dở dang: LevelDB/Badger được khôi phục vào
+thư mục mới rồi đổi tên, thư mục cũ giữ lại ở <dir>.pre-restore-<time>; SQL xoá và ghi trong
+một transaction.
+
+LevelDB và Badger chỉ cho phép một process mở, hãy dừng service trước khi dùng lệnh này
+hoặc tạo snapshot online qua POST /api/v1/admin/snapshots.`
+
+func runBackupCommand(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	out := fs.String("out", "", "Output file, default is a new file in Backup.Dir")
+	keyFile := fs.String("key-file", "", "Passphrase file, default is Backup.KeyFile")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), backupUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	passphrase, err := backup.LoadKey(firstNonEmpty(*keyFile, cfg.Backup.KeyFile))
+	if err != nil {
+		return err
+	}
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var snapshot backup.Snapshot
+	if *out == "" {
+		snapshot, err = backup.SaveSnapshot(cfg.Backup.Dir, db.KV(), passphrase, cfg.Backup.Keep)
+	} else {
+		snapshot, err = backup.WriteFile(*out, db.KV(), passphrase)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(snapshot)
+}
+
+func runRestoreCommand(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	in := fs.String("in", "", "Backup file to restore")
+	keyFile := fs.String("key-file", "", "Passphrase file, default is Backup.KeyFile")
+	force := fs.Bool("force", false, "Replace existing data in the store")
+	verifyOnly := fs.Bool("verify", false, "Only verify the backup file")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), backupUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New(backupUsage)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	passphrase, err := backup.LoadKey(firstNonEmpty(*keyFile, cfg.Backup.KeyFile))
+	if err != nil {
+		return err
+	}
+	if *verifyOnly {
+		manifest, err := backup.VerifyFile(*in, passphrase)
+		if err != nil {
+			return err
+		}
+		return printJSON(manifest)
+	}
+
+	if *force {
+		if dir := app.StoreDir(cfg); dir != "" {
+			return restoreSwap(cfg, dir, *in, passphrase)
+		}
+	}
+
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var manifest backup.Manifest
+	if *force {
+		manifest, err = backup.Replace(*in, db.KV(), passphrase)
+	} else {
+		manifest, err = backup.Restore(*in, db.KV(), passphrase)
+	}
+	if errors.Is(err, backup.ErrNotEmpty) {
+		return fmt.Errorf("%w, use -force to overwrite", err)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(manifest)
+}
+
+// restoreSwap khôi phục vào thư mục mới cạnh dir rồi đổi tên, lỗi giữa chừng chỉ xoá thư mục
+// mới; dữ liệu cũ được giữ ở <dir>.pre-restore-<time>
+func restoreSwap(cfg *config.AppConfig, dir string, in string, passphrase []byte) error {
+	// Mở thử store hiện tại để chắc chắn không có process nào đang giữ nó
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	db.Close()
+
+	stamp := time.Now().UTC().Format("20060102T150405")
+	fresh := dir + ".restore-" + stamp
+	db, err = app.OpenStoreAt(cfg, fresh)
+	if err != nil {
+		return err
+	}
+	manifest, err := backup.Restore(in, db.KV(), passphrase)
+	if closeErr := db.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(fresh)
+		return err
+	}
+
+	previous := dir + ".pre-restore-" + stamp
+	if err := os.Rename(dir, previous); err != nil {
+		os.RemoveAll(fresh)
+		return err
+	}
+	if err := os.Rename(fresh, dir); err != nil {
+		if rerr := os.Rename(previous, dir); rerr != nil {
+			return fmt.Errorf("%w (dữ liệu cũ ở %s, bản khôi phục ở %s)", err, previous, fresh)
+		}
+		return err
+	}
+	return printJSON(map[string]interface{}{"manifest": manifest, "previous": previous})
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"math/big"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/e2e"
+)
+
+const e2eUsage = `usage: cardvisa e2e [-artifacts contract/out] [-timeout 60s] [-acquirer-response <json>]
+
+Chạy luồng requestToken → submitToken → charge → UpdateTxStatus → MintUTXO trên chain giả lập
+trong process với acquirer giả. Cần build contract trước: cd contract && forge build.
+Lệnh trả mã lỗi khác 0 nếu một bước không qua, report JSON in ra stdout.`
+
+func runE2ECommand(args []string) error {
+	fs := flag.NewFlagSet("e2e", flag.ContinueOnError)
+	fs.Usage = func() { fs.Output().Write([]byte(e2eUsage + "\n")) }
+	artifacts := fs.String("artifacts", "contract/out", "forge build output directory")
+	timeout := fs.Duration("timeout", 60*time.Second, "Timeout of each step")
+	response := fs.String("acquirer-response", "", "Body returned by the fake acquirer")
+	amount := fs.Int64("amount", 100000, "Charge amount in token units")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := e2e.Run(e2e.Options{
+		ArtifactsDir:     *artifacts,
+		AcquirerResponse: *response,
+		StepTimeout:      *timeout,
+		Amount:           big.NewInt(*amount),
+	})
+	if perr := printJSON(report); perr != nil {
+		return perr
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const heldUsage = `usage: cardvisa held <list|approve|decline> [flags]
+
+  list
+  approve  -tx <txId> [-by <operator>]
+  decline  -tx <txId> [-by <operator>]
+
+approve/decline chỉ ghi quyết định, service áp dụng (gửi lại charge hoặc UpdateTxStatus thất bại)
+khi khởi động. LevelDB và Badger chỉ cho phép một process mở, hãy dừng service trước khi dùng lệnh này.`
+
+func runHeldCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(heldUsage)
+	}
+	fs := flag.NewFlagSet("held "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	txID := fs.String("tx", "", "Transaction ID of the held charge")
+	by := fs.String("by", "cli", "Operator recorded with the decision")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		charges, err := db.Charges().ListHeld()
+		if err != nil {
+			return err
+		}
+		return printJSON(charges)
+	case model.HeldApprove, model.HeldDecline:
+		held, err := db.Charges().GetHeld(*txID)
+		if err != nil {
+			return err
+		}
+		held.Decision = args[0]
+		held.DecidedBy = *by
+		if err := db.Charges().SaveHeld(held); err != nil {
+			return err
+		}
+		return printJSON(held)
+	}
+	return errors.New(heldUsage)
+}
@@ -21,6 +21,19 @@ var (
 	CONFIG_FILE_PATH string
 	LOG_LEVEL        int
 )
+
+// commands là các lệnh quản trị chạy thay cho service, vd: cardvisa merchant list
+var commands = map[string]func(args []string) error{
+	"merchant":   runMerchantCommand,
+	"held":       runHeldCommand,
+	"settlement": runSettlementCommand,
+	"reconcile":  runReconcileCommand,
+	"admin":      runAdminCommand,
+	"e2e":        runE2ECommand,
+	"backup":     runBackupCommand,
+	"restore":    runRestoreCommand,
+}
+
 func main() {
 	defer func() {
 		if r := recover(); r != nil {
@@ -28,6 +41,16 @@ func main() {
 		}
 	}()
 
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	flag.StringVar(&CONFIG_FILE_PATH, "config", defaultConfigPath, "Config path")
 	flag.StringVar(&CONFIG_FILE_PATH, "c", defaultConfigPath, "Config path (shorthand)")
 
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const merchantUsage = `usage: cardvisa merchant <set|get|list|delete> [flags]
+
+  set     -address <addr> [-mid <mId>] [-terminal <id>] [-fee-payer <n>] [-enabled=<bool>]
+          [-category <mcc>] [-regions <r1,r2>]
+          tạo mới cần -mid; merchant đã có chỉ đổi các trường được truyền
+  get     -address <addr>
+  list
+  delete  -address <addr>
+
+LevelDB và Badger chỉ cho phép một process mở, hãy dừng service trước khi dùng lệnh này.`
+
+func runMerchantCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(merchantUsage)
+	}
+	fs := flag.NewFlagSet("merchant "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	address := fs.String("address", "", "Merchant address on chain")
+	mID := fs.String("mid", "", "Acquirer merchant ID")
+	terminalID := fs.String("terminal", "", "Acquirer terminal ID")
+	feePayer := fs.Int("fee-payer", 1, "Fee payer policy sent to acquirer")
+	enabled := fs.Bool("enabled", true, "Accept charges for this merchant")
+	category := fs.String("category", "", "Merchant category (MCC) used by risk rules")
+	regions := fs.String("regions", "", "Comma-separated regions allowed for this merchant, empty for any")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if args[0] != "list" && !common.IsHexAddress(*address) {
+		return fmt.Errorf("invalid -address %q", *address)
+	}
+	switch args[0] {
+	case "set":
+		m, err := db.Merchants().Get(common.HexToAddress(*address))
+		if errors.Is(err, database.ErrMerchantNotFound) {
+			m = model.Merchant{Address: *address, FeePayer: *feePayer, Enabled: *enabled}
+		} else if err != nil {
+			return err
+		}
+		// Chỉ ghi đè các trường có truyền flag, giữ nguyên phần còn lại của merchant đã đăng ký
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "mid":
+				m.MID = *mID
+			case "terminal":
+				m.TerminalID = *terminalID
+			case "fee-payer":
+				m.FeePayer = *feePayer
+			case "enabled":
+				m.Enabled = *enabled
+			case "category":
+				m.Category = *category
+			case "regions":
+				m.AllowedRegions = splitList(*regions)
+			}
+		})
+		if err := db.Merchants().Save(m); err != nil {
+			return err
+		}
+		return printJSON(m)
+	case "get":
+		m, err := db.Merchants().Get(common.HexToAddress(*address))
+		if err != nil {
+			return err
+		}
+		return printJSON(m)
+	case "list":
+		merchants, err := db.Merchants().List()
+		if err != nil {
+			return err
+		}
+		return printJSON(merchants)
+	case "delete":
+		return db.Merchants().Delete(common.HexToAddress(*address))
+	}
+	return errors.New(merchantUsage)
+}
+
+func splitList(v string) []string {
+	list := []string{}
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+)
+
+const reconcileUsage = `usage: cardvisa reconcile -file <settlement.csv|settlement.json> [-repair] [-out report.json]
+
+LevelDB chỉ cho phép một process mở, hãy dừng service hoặc dùng Reconcile.InboxDir khi service đang chạy.`
+
+func runReconcileCommand(args []string) error {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	file := fs.String("file", "", "Acquirer settlement file")
+	repair := fs.Bool("repair", false, "Repair mismatches that can be fixed automatically")
+	out := fs.String("out", "", "Write report to file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New(reconcileUsage)
+	}
+	records, err := utils.ParseSettlementFile(*file)
+	if err != nil {
+		return err
+	}
+	a, err := app.NewApp(*configPath, defaultLogLevel)
+	if err != nil {
+		return err
+	}
+	defer a.Stop()
+
+	report, err := a.CardHandler.Reconcile(records, filepath.Base(*file), *repair)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return printJSON(report)
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
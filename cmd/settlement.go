@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/app"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const settlementUsage = `usage: cardvisa settlement <list|resolve> [flags]
+
+  list     [-review]
+  resolve  -tx <txId> -by <operator> [-note <text>] [-step <step>]
+
+resolve gỡ NeedsReview của saga sau khi đã kiểm tra on-chain, ghi lại người gỡ và ghi chú.
+-step đặt lại bước của saga: status_updated để mint (chỉ mint nếu getPoolInfo chưa có pool),
+utxo_minted để kiểm tra pool lại, pool_verified nếu pool on-chain đã đúng.
+Service retry saga ở chu kỳ ResumeSettlements kế tiếp. LevelDB và Badger chỉ cho phép một
+process mở, hãy dừng service trước khi dùng lệnh này.`
+
+func runSettlementCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(settlementUsage)
+	}
+	fs := flag.NewFlagSet("settlement "+args[0], flag.ContinueOnError)
+	configPath := fs.String("config", defaultConfigPath, "Config path")
+	review := fs.Bool("review", false, "Only list settlements waiting for review")
+	txID := fs.String("tx", "", "Transaction ID of the settlement")
+	by := fs.String("by", "", "Operator clearing the review")
+	note := fs.String("note", "", "Review note")
+	step := fs.String("step", "", "Step to resume the saga from")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	db, err := app.OpenStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch args[0] {
+	case "list":
+		settlements, err := db.Settlements().List()
+		if err != nil {
+			return err
+		}
+		if *review {
+			waiting := []model.Settlement{}
+			for _, s := range settlements {
+				if s.NeedsReview {
+					waiting = append(waiting, s)
+				}
+			}
+			settlements = waiting
+		}
+		return printJSON(settlements)
+	case "resolve":
+		if *by == "" {
+			return errors.New("-by is required")
+		}
+		if *step != "" && !model.ValidSettlementStep(*step) {
+			return fmt.Errorf("unknown settlement step %q", *step)
+		}
+		saga, err := db.Settlements().Get(*txID)
+		if err != nil {
+			return err
+		}
+		if !saga.NeedsReview {
+			return fmt.Errorf("settlement %s is not waiting for review", saga.TxID)
+		}
+		if *step != "" {
+			saga.Step = *step
+		}
+		saga.NeedsReview = false
+		saga.PoolChecks = 0
+		saga.ReviewedBy = *by
+		saga.ReviewNote = *note
+		saga.ReviewedAt = time.Now().Unix()
+		saga.UpdatedAt = saga.ReviewedAt
+		if err := db.Settlements().Save(saga); err != nil {
+			return err
+		}
+		return printJSON(saga)
+	}
+	return errors.New(settlementUsage)
+}
@@ -563,6 +563,23 @@ contract CardTokenManager is Ownable {
         return mTokenIdToLastTxID[tokenId];
     }
     function UpdateTxStatus(bytes32 tokenId,string memory txID,TxStatus status, uint64 atTime, string memory reason) external onlyBEProcessor {
+        _updateTxStatus(tokenId, txID, status, atTime, reason);
+    }
+    // Gộp nhiều UpdateTxStatus vào một giao dịch, áp dụng đúng thứ tự truyền vào.
+    // Phần tử không hợp lệ bị bỏ qua (ok[i] = false) thay vì revert cả lô.
+    function batchUpdateTxStatus(TxStatusUpdate[] calldata updates) external onlyBEProcessor returns (bool[] memory ok) {
+        ok = new bool[](updates.length);
+        for (uint256 i = 0; i < updates.length; i++) {
+            TxStatusUpdate calldata u = updates[i];
+            if (bytes(u.txID).length == 0) {
+                continue;
+            }
+            _updateTxStatus(u.tokenId, u.txID, u.status, u.atTime, u.reason);
+            ok[i] = true;
+        }
+        return ok;
+    }
+    function _updateTxStatus(bytes32 tokenId,string memory txID,TxStatus status, uint64 atTime, string memory reason) internal {
         mTxIdToStatus[txID] = TransactionStatus({
             txID : txID,
             status : status,
@@ -570,7 +587,6 @@ contract CardTokenManager is Ownable {
             reason : reason
         });
         mTokenIdToLastTxID[tokenId] = txID;
-        
     }
     function MintUTXO(uint256 parentValue,address ownerPool,string memory transactionID)external onlyAdmin returns (address newPool,bytes32 parentHash){
         parentHash = keccak256(abi.encodePacked(msg.sender, parentValue, block.timestamp, block.number));
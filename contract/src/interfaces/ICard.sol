@@ -39,4 +39,13 @@ pragma solidity ^0.8.20;
         TxStatus status;
         uint64 atTime;
         string reason;
+    }
+
+    // Một phần tử trong batchUpdateTxStatus
+    struct TxStatusUpdate{
+        bytes32 tokenId;
+        string txID;
+        TxStatus status;
+        uint64 atTime;
+        string reason;
     }
\ No newline at end of file
@@ -726,4 +726,88 @@ contract CardTokenManagerTest is Test {
     //     );
 
     // }
+
+    // ===== BATCH UPDATE TX STATUS TESTS =====
+
+    function batchItem(bytes32 tokenId, string memory txID, TxStatus status, uint64 atTime, string memory reason) internal pure returns (TxStatusUpdate memory) {
+        return TxStatusUpdate({tokenId: tokenId, txID: txID, status: status, atTime: atTime, reason: reason});
+    }
+
+    function testBatchUpdateTxStatusPerItemOutcome() public {
+        TxStatusUpdate[] memory updates = new TxStatusUpdate[](3);
+        updates[0] = batchItem(TEST_TOKEN_ID, "tx-1", TxStatus.SUCCESS, 100, "success");
+        updates[1] = batchItem(TEST_TOKEN_ID, "", TxStatus.SUCCESS, 101, "empty txID");
+        updates[2] = batchItem(TEST_TOKEN_ID, "tx-2", TxStatus.FAIL, 102, "declined");
+
+        vm.prank(beProcessor);
+        bool[] memory ok = cardTokenManager.batchUpdateTxStatus(updates);
+        assertEq(ok.length, 3);
+        assertTrue(ok[0]);
+        assertFalse(ok[1], "empty txID should be skipped");
+        assertTrue(ok[2]);
+
+        TransactionStatus memory tx1 = cardTokenManager.getTx("tx-1");
+        assertEq(uint8(tx1.status), uint8(TxStatus.SUCCESS));
+        assertEq(tx1.atTime, 100);
+        TransactionStatus memory tx2 = cardTokenManager.getTx("tx-2");
+        assertEq(uint8(tx2.status), uint8(TxStatus.FAIL));
+        assertEq(tx2.reason, "declined");
+        // Áp dụng đúng thứ tự nên txID cuối của token là phần tử hợp lệ cuối cùng
+        assertEq(cardTokenManager.getLastTxID(TEST_TOKEN_ID), "tx-2");
+    }
+
+    function testBatchUpdateTxStatusSameTxAppliesInOrder() public {
+        TxStatusUpdate[] memory updates = new TxStatusUpdate[](2);
+        updates[0] = batchItem(TEST_TOKEN_ID, "tx-1", TxStatus.BEING_PROCESSED, 100, "being processed");
+        updates[1] = batchItem(TEST_TOKEN_ID, "tx-1", TxStatus.SUCCESS, 101, "success");
+
+        vm.prank(beProcessor);
+        cardTokenManager.batchUpdateTxStatus(updates);
+        TransactionStatus memory tx1 = cardTokenManager.getTx("tx-1");
+        assertEq(uint8(tx1.status), uint8(TxStatus.SUCCESS));
+        assertEq(tx1.atTime, 101);
+    }
+
+    function testOnlyBEProcessorCanBatchUpdateTxStatus() public {
+        TxStatusUpdate[] memory updates = new TxStatusUpdate[](1);
+        updates[0] = batchItem(TEST_TOKEN_ID, "tx-1", TxStatus.SUCCESS, 100, "success");
+
+        vm.prank(user1);
+        vm.expectRevert("Only backend processor allowed");
+        cardTokenManager.batchUpdateTxStatus(updates);
+
+        // Owner/admin cũng không được gọi thay backend processor, giống UpdateTxStatus
+        vm.prank(admin);
+        vm.expectRevert("Only backend processor allowed");
+        cardTokenManager.batchUpdateTxStatus(updates);
+
+        assertEq(uint8(cardTokenManager.getTx("tx-1").status), uint8(TxStatus.FAIL), "state should be untouched");
+    }
+
+    function testBatchUpdateTxStatusMatchesUpdateTxStatus() public {
+        bytes32 singleToken = keccak256("SINGLE_TOKEN");
+        bytes32 batchToken = keccak256("BATCH_TOKEN");
+        vm.prank(beProcessor);
+        cardTokenManager.UpdateTxStatus(singleToken, "tx-single", TxStatus.BEING_PROCESSED, 200, "being processed");
+
+        TxStatusUpdate[] memory updates = new TxStatusUpdate[](1);
+        updates[0] = batchItem(batchToken, "tx-batch", TxStatus.BEING_PROCESSED, 200, "being processed");
+        vm.prank(beProcessor);
+        cardTokenManager.batchUpdateTxStatus(updates);
+
+        TransactionStatus memory single = cardTokenManager.getTx("tx-single");
+        TransactionStatus memory batch = cardTokenManager.getTx("tx-batch");
+        assertEq(batch.txID, "tx-batch");
+        assertEq(uint8(batch.status), uint8(single.status));
+        assertEq(batch.atTime, single.atTime);
+        assertEq(batch.reason, single.reason);
+        assertEq(cardTokenManager.getLastTxID(singleToken), "tx-single");
+        assertEq(cardTokenManager.getLastTxID(batchToken), "tx-batch");
+
+        // requestUpdateTxStatus chỉ nhận giao dịch BEING_PROCESSED, hai đường ghi phải cho cùng kết quả
+        vm.prank(user1);
+        cardTokenManager.requestUpdateTxStatus(singleToken, "tx-single");
+        vm.prank(user1);
+        cardTokenManager.requestUpdateTxStatus(batchToken, "tx-batch");
+    }
 }
\ No newline at end of file
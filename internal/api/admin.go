@@ -0,0 +1,137 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/network"
+)
+
+const adminKeyHeader = "X-Admin-Key"
+
+// requireAdmin chỉ cho request có header X-Admin-Key khớp AdminApiKey; API admin tắt nếu chưa cấu hình key
+func (s *Server) requireAdmin(c *gin.Context) {
+	if s.config.AdminApiKey == "" {
+		errorJSON(c, http.StatusServiceUnavailable, errors.New("admin API is not configured"))
+		return
+	}
+	key := c.GetHeader(adminKeyHeader)
+	if subtle.ConstantTimeCompare([]byte(key), []byte(s.config.AdminApiKey)) != 1 {
+		errorJSON(c, http.StatusUnauthorized, errors.New("invalid admin key"))
+		return
+	}
+	c.Next()
+}
+
+func merchantAddress(c *gin.Context) (common.Address, bool) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		errorJSON(c, http.StatusBadRequest, errors.New("invalid address"))
+		return common.Address{}, false
+	}
+	return common.HexToAddress(address), true
+}
+
+func (s *Server) listMerchants(c *gin.Context) {
+	merchants, err := s.handler.DB.Merchants().List()
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"merchants": merchants})
+}
+
+func (s *Server) getMerchant(c *gin.Context) {
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	merchant, err := s.handler.DB.Merchants().Get(address)
+	if errors.Is(err, database.ErrMerchantNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, merchant)
+}
+
+func (s *Server) putMerchant(c *gin.Context) {
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	var merchant model.Merchant
+	if err := c.ShouldBindJSON(&merchant); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	merchant.Address = address.Hex()
+	if err := s.handler.DB.Merchants().Save(merchant); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, merchant)
+}
+
+func (s *Server) deleteMerchant(c *gin.Context) {
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	if err := s.handler.DB.Merchants().Delete(address); err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (s *Server) listHeldCharges(c *gin.Context) {
+	charges, err := s.handler.DB.Charges().ListHeld()
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"charges": charges})
+}
+
+func (s *Server) approveHeldCharge(c *gin.Context) {
+	s.releaseHeldCharge(c, true)
+}
+
+func (s *Server) declineHeldCharge(c *gin.Context) {
+	s.releaseHeldCharge(c, false)
+}
+
+func (s *Server) releaseHeldCharge(c *gin.Context, approve bool) {
+	err := s.handler.ReleaseHeldCharge(c.Param("txId"), approve)
+	if errors.Is(err, database.ErrHeldChargeNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, network.ErrHeldChargeReleasing) {
+		errorJSON(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+func (s *Server) listLockAudits(c *gin.Context) {
+	audits, err := s.handler.DB.Declines().ListLockAudits()
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"audits": audits})
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+type challengeResultRequest struct {
+	Passed bool   `json:"passed"`
+	OTP    string `json:"otp"`
+}
+
+// challengeResponse là phần challenge trả cho ví, không gồm tokenId, cardHash hay MID
+type challengeResponse struct {
+	TxID        string `json:"txId"`
+	Type        string `json:"type"`
+	RedirectURL string `json:"redirectUrl,omitempty"`
+	Status      string `json:"status"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+func newChallengeResponse(challenge model.Challenge) challengeResponse {
+	return challengeResponse{
+		TxID:        challenge.TxID,
+		Type:        challenge.Type,
+		RedirectURL: challenge.RedirectURL,
+		Status:      challenge.Status,
+		CreatedAt:   challenge.CreatedAt,
+		UpdatedAt:   challenge.UpdatedAt,
+	}
+}
+
+func (s *Server) getChallenge(c *gin.Context) {
+	challenge, err := s.handler.GetChallenge(c.Param("txId"))
+	if errors.Is(err, database.ErrChallengeNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, newChallengeResponse(challenge))
+}
+
+// postChallengeResult nhận kết quả xác thực từ acquirer/ACS (hoặc backend ví chuyển tiếp OTP), header
+// X-Signature là hex(HMAC-SHA256(ChallengeSecret, body)) vì kết quả này quyết định charge thành công hay thất bại
+func (s *Server) postChallengeResult(c *gin.Context) {
+	if s.config.ChallengeSecret == "" {
+		errorJSON(c, http.StatusServiceUnavailable, errors.New("challenge result is not configured"))
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	if !validSignature(s.config.ChallengeSecret, body, c.GetHeader(signatureHeader)) {
+		errorJSON(c, http.StatusUnauthorized, errors.New("invalid signature"))
+		return
+	}
+	var req challengeResultRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	challenge, err := s.handler.ResolveChallenge(c.Param("txId"), req.Passed, req.OTP)
+	if errors.Is(err, database.ErrChallengeNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	c.JSON(http.StatusOK, newChallengeResponse(challenge))
+}
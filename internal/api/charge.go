@@ -0,0 +1,73 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+)
+
+const defaultChargeLimit = 100
+
+func (s *Server) getCharge(c *gin.Context) {
+	charge, err := s.handler.DB.Charges().Get(c.Param("txId"))
+	if errors.Is(err, database.ErrChargeNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, charge)
+}
+
+// listCharges lọc theo đúng một trong các query token, merchant, status
+func (s *Server) listCharges(c *gin.Context) {
+	limit := defaultChargeLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			errorJSON(c, http.StatusBadRequest, errors.New("invalid limit"))
+			return
+		}
+		limit = n
+	}
+	var index, value string
+	for _, key := range []string{database.ChargeByToken, database.ChargeByMerchant, database.ChargeByStatus} {
+		if v := c.Query(key); v != "" {
+			index, value = key, v
+			break
+		}
+	}
+	switch index {
+	case database.ChargeByToken:
+		value = strings.TrimPrefix(strings.ToLower(value), "0x")
+	case database.ChargeByMerchant:
+		value = common.HexToAddress(value).Hex()
+	}
+	if index == "" {
+		errorJSON(c, http.StatusBadRequest, errors.New("one of token, merchant or status is required"))
+		return
+	}
+	charges, err := s.handler.DB.Charges().ListBy(index, value, limit)
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"charges": charges})
+}
+
+// getChargeStatus trả về trạng thái on-chain (getTx) của giao dịch
+func (s *Server) getChargeStatus(c *gin.Context) {
+	status, err := s.handler.ChainTxStatus(c.Param("txId"))
+	if err != nil {
+		errorJSON(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/network"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+)
+
+type cardLockRequest struct {
+	Locked bool   `json:"locked"`
+	Reason string `json:"reason"`
+}
+
+type tokenActiveRequest struct {
+	Active bool   `json:"active"`
+	Reason string `json:"reason"`
+}
+
+type contractLockRequest struct {
+	Locked bool `json:"locked"`
+}
+
+type contractAdminRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type processorRequest struct {
+	Address string `json:"address" binding:"required"`
+}
+
+type backendPubKeyRequest struct {
+	PubKey string `json:"pubKey" binding:"required"`
+}
+
+type cleanUsageRequest struct {
+	Before uint64 `json:"before" binding:"required"`
+}
+
+// deployment chọn card contract theo query ?contract=<Name>, mặc định là deployment chính
+func (s *Server) deployment(c *gin.Context) (*network.CardHandler, bool) {
+	h, ok := s.handler.Deployment(c.Query("contract"))
+	if !ok {
+		errorJSON(c, http.StatusNotFound, errors.New("unknown contract"))
+	}
+	return h, ok
+}
+
+// contractResult trả kết quả transaction: 422 kèm lý do nếu contract revert,
+// 502 nếu chain không phản hồi
+func contractResult(c *gin.Context, result *services.TxResult, err error) {
+	var txErr *services.TxError
+	if errors.As(err, &txErr) {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"error": gin.H{
+				"code":    "transaction_reverted",
+				"message": txErr.Reason,
+				"txHash":  txErr.TxHash.Hex(),
+			},
+		})
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "result": result})
+}
+
+func (s *Server) getMerchantRule(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	rule, err := h.Query().MerchantRule(address)
+	if err != nil {
+		errorJSON(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) getGlobalRule(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	rule, err := h.Query().GlobalRule()
+	if err != nil {
+		errorJSON(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) getTokenState(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	tokenId, err := parseTokenID(c.Param("tokenId"))
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	token, err := h.Query().Token(tokenId)
+	if err != nil {
+		errorJSON(c, http.StatusBadGateway, err)
+		return
+	}
+	c.JSON(http.StatusOK, token)
+}
+
+func (s *Server) putMerchantRule(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	var rule model.MerchantRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	rule.Merchant = address.Hex()
+	result, err := h.Contract().SetMerchantRule(rule)
+	contractResult(c, result, err)
+}
+
+func (s *Server) putGlobalRule(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	var rule model.GlobalRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.Contract().SetGlobalRule(rule)
+	contractResult(c, result, err)
+}
+
+func (s *Server) putCardLock(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	cardHash, err := parseTokenID(c.Param("cardHash"))
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, errors.New("invalid cardHash"))
+		return
+	}
+	var req cardLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	err = h.SetCardLocked(cardHash, req.Locked, req.Reason)
+	contractResult(c, nil, err)
+}
+
+func (s *Server) putTokenActive(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	tokenId, err := parseTokenID(c.Param("tokenId"))
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	var req tokenActiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	err = h.SetTokenActive(tokenId, req.Active, req.Reason)
+	contractResult(c, nil, err)
+}
+
+func (s *Server) putContractLock(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	var req contractLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.Contract().SetLock(req.Locked)
+	contractResult(c, result, err)
+}
+
+func (s *Server) putContractAdmin(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	address, ok := merchantAddress(c)
+	if !ok {
+		return
+	}
+	var req contractAdminRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.Contract().SetAdmin(address, req.Enabled)
+	contractResult(c, result, err)
+}
+
+func (s *Server) putProcessor(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	var req processorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		errorJSON(c, http.StatusBadRequest, errors.New("invalid address"))
+		return
+	}
+	result, err := h.Contract().SetProcessor(common.HexToAddress(req.Address))
+	contractResult(c, result, err)
+}
+
+func (s *Server) putBackendPubKey(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	var req backendPubKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	pubKey := common.FromHex(req.PubKey)
+	if len(pubKey) == 0 {
+		errorJSON(c, http.StatusBadRequest, errors.New("invalid pubKey"))
+		return
+	}
+	result, err := h.Contract().SetBackendPubKey(pubKey)
+	contractResult(c, result, err)
+}
+
+func (s *Server) postCleanUsage(c *gin.Context) {
+	h, ok := s.deployment(c)
+	if !ok {
+		return
+	}
+	var req cleanUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	result, err := h.Contract().CleanUsage(req.Before)
+	contractResult(c, result, err)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+)
+
+func (s *Server) getHealth(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReady kiểm tra leveldb, RPC chain và hàng đợi transaction của từng deployment; 503 nếu một
+// trong số đó không dùng được
+func (s *Server) getReady(c *gin.Context) {
+	checks := gin.H{}
+	ready := true
+	checks["database"] = "ok"
+
+	contracts := gin.H{}
+	for _, h := range s.handler.Deployments() {
+		contract := h.ContractConfig()
+		check := gin.H{"address": contract.Address}
+		lastBlock, ok, err := h.LastBlock()
+		switch {
+		case err != nil:
+			checks["database"] = err.Error()
+			ready = false
+		case ok:
+			check["lastBlock"] = lastBlock
+		}
+
+		if _, err := utils.GetLatestBlockNumber(contract.RpcURL); err != nil {
+			check["chain"] = err.Error()
+			ready = false
+		} else {
+			check["chain"] = "ok"
+		}
+
+		// Job cleanUsage lỗi không làm service hết ready, chỉ báo trạng thái để giám sát
+		if s.config.CleanUsage.Enabled {
+			if state, err := s.handler.DB.Maintenance().CleanUsageState(contract.Name); err == nil {
+				check["cleanUsage"] = state
+			}
+		}
+		// Các deployment cùng chain dùng chung một hàng đợi nên số liệu có thể trùng nhau
+		stats := h.Contract().QueueStats()
+		check["txQueue"] = stats
+		// Sender bị giữ bởi transaction không có receipt thì mọi transaction sau đều phải chờ
+		if stats.StalledSince != 0 {
+			ready = false
+		}
+		contracts[contract.Name] = check
+	}
+	checks["contracts"] = contracts
+
+	status := http.StatusOK
+	checks["status"] = "ready"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		checks["status"] = "not_ready"
+	}
+	c.JSON(status, checks)
+}
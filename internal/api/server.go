@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/network"
+)
+
+type Server struct {
+	config  *config.AppConfig
+	handler *network.CardHandler
+	http    *http.Server
+	// snapshotMu giữ trong lúc POST /admin/snapshots đang ghi, chỉ cho một snapshot chạy
+	snapshotMu sync.Mutex
+}
+
+// NewServer gắn các route của service vào engine và chuẩn bị http.Server lắng nghe ở API_PORT
+func NewServer(config *config.AppConfig, handler *network.CardHandler, engine *gin.Engine) *Server {
+	s := &Server{
+		config:  config,
+		handler: handler,
+		http: &http.Server{
+			Addr:    config.API_PORT,
+			Handler: engine,
+		},
+	}
+	s.register(engine)
+	return s
+}
+
+func (s *Server) register(r *gin.Engine) {
+	r.Use(gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
+		errorJSON(c, http.StatusInternalServerError, errors.New("internal error"))
+	}))
+	r.NoRoute(func(c *gin.Context) {
+		errorJSON(c, http.StatusNotFound, errors.New("route not found"))
+	})
+	r.GET("/healthz", s.getHealth)
+	r.GET("/readyz", s.getReady)
+
+	v1 := r.Group("/api/v1")
+	v1.GET("/challenges/:txId", s.getChallenge)
+	v1.POST("/challenges/:txId/result", s.postChallengeResult)
+	v1.POST("/webhooks/acquirer", s.postAcquirerWebhook)
+	v1.GET("/charges/:txId/status", s.getChargeStatus)
+
+	admin := v1.Group("/admin", s.requireAdmin)
+	admin.GET("/merchants", s.listMerchants)
+	admin.GET("/merchants/:address", s.getMerchant)
+	admin.PUT("/merchants/:address", s.putMerchant)
+	admin.DELETE("/merchants/:address", s.deleteMerchant)
+	admin.GET("/held-charges", s.listHeldCharges)
+	admin.POST("/held-charges/:txId/approve", s.approveHeldCharge)
+	admin.POST("/held-charges/:txId/decline", s.declineHeldCharge)
+	admin.GET("/lock-audits", s.listLockAudits)
+	// Bản ghi charge có cardHash và phản hồi thô của acquirer nên chỉ dành cho admin
+	admin.GET("/charges", s.listCharges)
+	admin.GET("/charges/:txId", s.getCharge)
+	// Token trả về cardHash và thẻ đã che (last4, hạn thẻ) nên cũng chỉ dành cho admin
+	admin.GET("/tokens/:tokenId", s.getToken)
+	admin.GET("/users/:address/tokens", s.listUserTokens)
+	admin.POST("/snapshots", s.postSnapshot)
+
+	contract := admin.Group("/contract")
+	contract.GET("/merchant-rules/:address", s.getMerchantRule)
+	contract.PUT("/merchant-rules/:address", s.putMerchantRule)
+	contract.GET("/global-rule", s.getGlobalRule)
+	contract.PUT("/global-rule", s.putGlobalRule)
+	contract.GET("/tokens/:tokenId", s.getTokenState)
+	contract.PUT("/cards/:cardHash/lock", s.putCardLock)
+	contract.PUT("/tokens/:tokenId/active", s.putTokenActive)
+	contract.PUT("/lock", s.putContractLock)
+	contract.PUT("/admins/:address", s.putContractAdmin)
+	contract.PUT("/processor", s.putProcessor)
+	contract.PUT("/backend-pubkey", s.putBackendPubKey)
+	contract.POST("/clean-usage", s.postCleanUsage)
+}
+
+// Start chạy HTTP server, trả về nil khi server bị Shutdown
+func (s *Server) Start() error {
+	err := s.http.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown dừng nhận request mới và chờ các request đang xử lý hoàn tất
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+// errorJSON trả lỗi theo dạng {"error": {"code": "...", "message": "..."}}
+func errorJSON(c *gin.Context, status int, err error) {
+	code := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": gin.H{
+			"code":    code,
+			"message": err.Error(),
+		},
+	})
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// signatureHeader chứa hex(HMAC-SHA256(secret, body)) của các callback từ acquirer/ACS
+const signatureHeader = "X-Signature"
+
+func validSignature(secret string, body []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/backup"
+)
+
+// postSnapshot ghi snapshot mã hoá của store vào Backup.Dir trong khi service vẫn chạy
+func (s *Server) postSnapshot(c *gin.Context) {
+	if !s.snapshotMu.TryLock() {
+		errorJSON(c, http.StatusConflict, errors.New("a snapshot is already running"))
+		return
+	}
+	defer s.snapshotMu.Unlock()
+
+	passphrase, err := backup.LoadKey(s.config.Backup.KeyFile)
+	if err != nil {
+		errorJSON(c, http.StatusServiceUnavailable, err)
+		return
+	}
+	snapshot, err := backup.SaveSnapshot(s.config.Backup.Dir, s.handler.DB.KV(), passphrase, s.config.Backup.Keep)
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusCreated, snapshot)
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+type tokenResponse struct {
+	model.TokenInfo
+	Card *model.MaskedCard `json:"card,omitempty"`
+}
+
+func parseTokenID(v string) ([32]byte, error) {
+	var tokenId [32]byte
+	b := common.FromHex(v)
+	if len(b) != 32 {
+		return tokenId, errors.New("invalid tokenId")
+	}
+	copy(tokenId[:], b)
+	return tokenId, nil
+}
+
+func (s *Server) getToken(c *gin.Context) {
+	tokenId, err := parseTokenID(c.Param("tokenId"))
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	info, err := s.handler.DB.Tokens().Info(tokenId)
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	card, err := s.handler.MaskedCard(tokenId)
+	if err != nil {
+		errorJSON(c, http.StatusNotFound, errors.New("token not found"))
+		return
+	}
+	c.JSON(http.StatusOK, tokenResponse{TokenInfo: info, Card: &card})
+}
+
+func (s *Server) listUserTokens(c *gin.Context) {
+	address := c.Param("address")
+	if !common.IsHexAddress(address) {
+		errorJSON(c, http.StatusBadRequest, errors.New("invalid address"))
+		return
+	}
+	infos, err := s.handler.DB.Tokens().ListInfosByUser(address)
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	tokens := make([]tokenResponse, 0, len(infos))
+	for _, info := range infos {
+		resp := tokenResponse{TokenInfo: info}
+		if tokenId, err := parseTokenID(info.TokenID); err == nil {
+			if card, err := s.handler.MaskedCard(tokenId); err == nil {
+				resp.Card = &card
+			}
+		}
+		tokens = append(tokens, resp)
+	}
+	c.JSON(http.StatusOK, gin.H{"user": strings.ToLower(address), "tokens": tokens})
+}
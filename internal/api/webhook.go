@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+// postAcquirerWebhook nhận callback của acquirer, header X-Signature là hex(HMAC-SHA256(WebhookSecret, body))
+func (s *Server) postAcquirerWebhook(c *gin.Context) {
+	if s.config.WebhookSecret == "" {
+		errorJSON(c, http.StatusServiceUnavailable, errors.New("webhook is not configured"))
+		return
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	if !validSignature(s.config.WebhookSecret, body, c.GetHeader(signatureHeader)) {
+		errorJSON(c, http.StatusUnauthorized, errors.New("invalid signature"))
+		return
+	}
+	var update model.AcquirerWebhook
+	if err := json.Unmarshal(body, &update); err != nil {
+		errorJSON(c, http.StatusBadRequest, err)
+		return
+	}
+	if update.TxID == "" {
+		errorJSON(c, http.StatusBadRequest, errors.New("tx_id is required"))
+		return
+	}
+	err = s.handler.HandleAcquirerWebhook(update)
+	if errors.Is(err, database.ErrChargeNotFound) {
+		errorJSON(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(c, http.StatusInternalServerError, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
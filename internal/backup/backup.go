@@ -0,0 +1,296 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+)
+
+const (
+	// Số key ghi trong một Update khi khôi phục
+	restoreBatchSize = 1000
+
+	fileExt     = ".cvbak"
+	checksumExt = ".sha256"
+)
+
+// ErrNotEmpty là lỗi Restore khi store đích đã có dữ liệu
+var ErrNotEmpty = errors.New("backup: target store is not empty")
+
+// Snapshot là kết quả SaveSnapshot
+type Snapshot struct {
+	Path     string   `json:"path"`
+	Size     int64    `json:"size"`
+	SHA256   string   `json:"sha256"`
+	Manifest Manifest `json:"manifest"`
+}
+
+// LoadKey đọc passphrase từ key file, bỏ khoảng trắng ở hai đầu
+func LoadKey(path string) ([]byte, error) {
+	if path == "" {
+		return nil, errors.New("backup: key file is not configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("backup: key file %s is empty", path)
+	}
+	return key, nil
+}
+
+// Write ghi toàn bộ kv vào w từ một bản chụp nhất quán
+func Write(w io.Writer, kv database.KV, passphrase []byte) (Manifest, error) {
+	enc, err := newEncryptWriter(w, passphrase)
+	if err != nil {
+		return Manifest{}, err
+	}
+	records := newRecordWriter(enc, time.Now().Unix())
+	err = kv.View(func(view database.Reader) error {
+		return view.Iterate(nil, records.Write)
+	})
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest, err := records.Finish()
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, enc.Close()
+}
+
+// Verify giải mã toàn bộ r và kiểm tra checksum mà không ghi gì
+func Verify(r io.Reader, passphrase []byte) (Manifest, error) {
+	dec, err := newDecryptReader(r, passphrase)
+	if err != nil {
+		return Manifest{}, err
+	}
+	return readRecords(dec, nil)
+}
+
+// VerifyFile kiểm tra file backup, kể cả file .sha256 đi kèm nếu có
+func VerifyFile(path string, passphrase []byte) (Manifest, error) {
+	if err := checkSidecar(path); err != nil {
+		return Manifest{}, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer f.Close()
+	return Verify(f, passphrase)
+}
+
+// Restore ghi nội dung file backup vào kv rỗng. File được kiểm tra trọn vẹn trước khi ghi key
+// đầu tiên; record được ghi theo từng Update restoreBatchSize key nên lỗi giữa chừng để lại kv
+// dở dang, caller khôi phục vào store mới rồi mới đưa vào dùng.
+func Restore(path string, kv database.KV, passphrase []byte) (Manifest, error) {
+	manifest, err := VerifyFile(path, passphrase)
+	if err != nil {
+		return manifest, err
+	}
+	empty := true
+	err = kv.Iterate(nil, func(_ []byte, _ []byte) error {
+		empty = false
+		return database.ErrStopIteration
+	})
+	if err != nil {
+		return manifest, err
+	}
+	if !empty {
+		return manifest, ErrNotEmpty
+	}
+
+	var batch [][2][]byte
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := kv.Update(func(txn database.Bucket) error {
+			for _, kv := range batch {
+				if err := txn.Put(kv[0], kv[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return err
+	}
+	err = readFile(path, passphrase, func(key []byte, value []byte) error {
+		batch = append(batch, [2][]byte{key, value})
+		if len(batch) >= restoreBatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, flush()
+}
+
+// Replace thay toàn bộ nội dung kv bằng file backup trong một Update: key hiện có bị xoá và
+// record được ghi trong cùng transaction, lỗi ở bất kỳ bước nào giữ nguyên dữ liệu cũ. Cả
+// snapshot nằm trong một transaction nên chỉ dùng cho backend SQL; LevelDB và Badger khôi
+// phục bằng Restore vào thư mục mới rồi đổi tên.
+func Replace(path string, kv database.KV, passphrase []byte) (Manifest, error) {
+	manifest, err := VerifyFile(path, passphrase)
+	if err != nil {
+		return manifest, err
+	}
+	err = kv.Update(func(txn database.Bucket) error {
+		var keys [][]byte
+		err := txn.Iterate(nil, func(key []byte, _ []byte) error {
+			keys = append(keys, append([]byte{}, key...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return readFile(path, passphrase, func(key []byte, value []byte) error {
+			return txn.Put(key, value)
+		})
+	})
+	return manifest, err
+}
+
+// readFile giải mã file backup và gọi fn cho từng record
+func readFile(path string, passphrase []byte, fn func(key []byte, value []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	dec, err := newDecryptReader(f, passphrase)
+	if err != nil {
+		return err
+	}
+	_, err = readRecords(dec, fn)
+	return err
+}
+
+// WriteFile ghi backup ra path qua file tạm rồi rename, kèm file path.sha256 chứa SHA-256
+// của ciphertext để kiểm tra khi sao chép mà không cần key
+func WriteFile(path string, kv database.KV, passphrase []byte) (Snapshot, error) {
+	snapshot := Snapshot{Path: path}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return snapshot, err
+	}
+	defer os.Remove(tmp.Name())
+
+	sum := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(tmp, sum)}
+	manifest, err := Write(counter, kv, passphrase)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Manifest = manifest
+	snapshot.Size = counter.n
+	snapshot.SHA256 = hex.EncodeToString(sum.Sum(nil))
+
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return snapshot, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return snapshot, err
+	}
+	sidecar := fmt.Sprintf("%s  %s\n", snapshot.SHA256, filepath.Base(path))
+	return snapshot, os.WriteFile(path+checksumExt, []byte(sidecar), 0o600)
+}
+
+// SaveSnapshot ghi backup mới vào dir và chỉ giữ lại keep bản mới nhất (keep <= 0 là giữ hết)
+func SaveSnapshot(dir string, kv database.KV, passphrase []byte, keep int) (Snapshot, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return Snapshot{}, err
+	}
+	name := "cardvisa-" + time.Now().UTC().Format("20060102T150405.000Z") + fileExt
+	snapshot, err := WriteFile(filepath.Join(dir, name), kv, passphrase)
+	if err != nil {
+		return snapshot, err
+	}
+	return snapshot, prune(dir, keep)
+}
+
+// prune xoá các snapshot cũ nhất; tên file theo thời gian nên sắp xếp tên là sắp xếp thời gian
+func prune(dir string, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "cardvisa-*"+fileExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+	for len(matches) > keep {
+		if err := os.Remove(matches[0]); err != nil {
+			return err
+		}
+		os.Remove(matches[0] + checksumExt)
+		matches = matches[1:]
+	}
+	return nil
+}
+
+// checkSidecar so SHA-256 của file với path.sha256 nếu file này tồn tại
+func checkSidecar(path string) error {
+	data, err := os.ReadFile(path + checksumExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("backup: empty checksum file %s", path+checksumExt)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum := sha256.New()
+	if _, err := io.Copy(sum, f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(sum.Sum(nil)) != fields[0] {
+		return ErrChecksum
+	}
+	return nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
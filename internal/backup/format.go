@@ -0,0 +1,357 @@
+// Package backup ghi và khôi phục snapshot mã hoá của Store (token vault, cursor, ledger charge).
+//
+// Định dạng file:
+//
+//	magic "CVBACKUP" | version (1 byte) | salt scrypt (16 byte) | nonce prefix (4 byte)
+//	frame*: độ dài ciphertext (uint32 big-endian) | AES-256-GCM(chunk)
+//
+// Nonce của frame thứ i là nonce prefix || i (uint64), AAD là header và cờ frame cuối nên
+// file bị cắt, đảo frame hay sửa header đều bị phát hiện. Plaintext nối các chunk lại là dãy
+// record 'r' | uvarint len | key | uvarint len | value, kết thúc bằng 'm' | uvarint len | Manifest
+// JSON; Manifest.SHA256 là SHA-256 của phần record.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	magic        = "CVBACKUP"
+	version      = 1
+	saltSize     = 16
+	prefixSize   = 4
+	headerSize   = len(magic) + 1 + saltSize + prefixSize
+	chunkSize    = 64 * 1024
+	maxFrameSize = chunkSize + 1024
+
+	recordTag   = 'r'
+	manifestTag = 'm'
+)
+
+var (
+	ErrBadPassphrase = errors.New("backup: wrong passphrase or corrupted data")
+	ErrTruncated     = errors.New("backup: file is truncated")
+	ErrChecksum      = errors.New("backup: checksum mismatch")
+)
+
+// Manifest mô tả nội dung snapshot, nằm trong phần mã hoá
+type Manifest struct {
+	Version   int    `json:"version"`
+	CreatedAt int64  `json:"createdAt"`
+	Records   int    `json:"records"`
+	SHA256    string `json:"sha256"`
+	// Số record theo namespace (prefix của key tới dấu _ đầu tiên), vd "charge_", "token_", "lastBlock"
+	Namespaces map[string]int `json:"namespaces"`
+}
+
+func namespaceOf(key []byte) string {
+	if i := bytes.IndexByte(key, '_'); i >= 0 {
+		return string(key[:i+1])
+	}
+	return string(key)
+}
+
+// deriveKey dùng scrypt (N=2^15, r=8, p=1) để passphrase yếu khó bị dò
+func deriveKey(passphrase []byte, salt []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("backup: empty passphrase")
+	}
+	return scrypt.Key(passphrase, salt, 1<<15, 8, 1, 32)
+}
+
+func newAEAD(passphrase []byte, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func frameNonce(prefix []byte, index uint64) []byte {
+	nonce := make([]byte, prefixSize+8)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint64(nonce[prefixSize:], index)
+	return nonce
+}
+
+func frameAAD(header []byte, final bool) []byte {
+	aad := append([]byte{}, header...)
+	if final {
+		return append(aad, 1)
+	}
+	return append(aad, 0)
+}
+
+// encryptWriter gom plaintext thành chunk và ghi mỗi chunk thành một frame
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint64
+	buf    []byte
+}
+
+func newEncryptWriter(w io.Writer, passphrase []byte) (*encryptWriter, error) {
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, version)
+	random := make([]byte, saltSize+prefixSize)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	header = append(header, random...)
+	aead, err := newAEAD(passphrase, random[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, header: header, prefix: random[saltSize:]}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	e.buf = append(e.buf, p...)
+	for len(e.buf) > chunkSize {
+		if err := e.flush(e.buf[:chunkSize], false); err != nil {
+			return 0, err
+		}
+		e.buf = e.buf[chunkSize:]
+	}
+	return len(p), nil
+}
+
+// Close ghi frame cuối; không đóng writer bên dưới
+func (e *encryptWriter) Close() error {
+	return e.flush(e.buf, true)
+}
+
+func (e *encryptWriter) flush(chunk []byte, final bool) error {
+	sealed := e.aead.Seal(nil, frameNonce(e.prefix, e.index), chunk, frameAAD(e.header, final))
+	e.index++
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := e.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader trả plaintext của các frame, io.EOF chỉ sau frame cuối
+type decryptReader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	header []byte
+	prefix []byte
+	index  uint64
+	buf    []byte
+	done   bool
+}
+
+func newDecryptReader(r io.Reader, passphrase []byte) (*decryptReader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrTruncated
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("backup: not a cardvisa backup file")
+	}
+	if header[len(magic)] != version {
+		return nil, fmt.Errorf("backup: unsupported version %d", header[len(magic)])
+	}
+	salt := header[len(magic)+1 : len(magic)+1+saltSize]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptReader{r: r, aead: aead, header: header, prefix: header[len(magic)+1+saltSize:]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) next() error {
+	var size [4]byte
+	if _, err := io.ReadFull(d.r, size[:]); err != nil {
+		return ErrTruncated
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return ErrBadPassphrase
+	}
+	sealed := make([]byte, n)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return ErrTruncated
+	}
+	nonce := frameNonce(d.prefix, d.index)
+	d.index++
+	// Frame cuối được nhận ra nhờ AAD, thử frame thường trước
+	plain, err := d.aead.Open(nil, nonce, sealed, frameAAD(d.header, false))
+	if err != nil {
+		plain, err = d.aead.Open(nil, nonce, sealed, frameAAD(d.header, true))
+		if err != nil {
+			return ErrBadPassphrase
+		}
+		d.done = true
+		// Không được có dữ liệu sau frame cuối
+		var extra [1]byte
+		if n, _ := d.r.Read(extra[:]); n > 0 {
+			return errors.New("backup: trailing data after final frame")
+		}
+	}
+	d.buf = plain
+	return nil
+}
+
+// recordWriter ghi record và tính checksum, Finish ghi manifest
+type recordWriter struct {
+	w        io.Writer
+	sum      hash.Hash
+	manifest Manifest
+}
+
+func newRecordWriter(w io.Writer, createdAt int64) *recordWriter {
+	return &recordWriter{
+		w:   w,
+		sum: sha256.New(),
+		manifest: Manifest{
+			Version:    version,
+			CreatedAt:  createdAt,
+			Namespaces: map[string]int{},
+		},
+	}
+}
+
+func (rw *recordWriter) Write(key []byte, value []byte) error {
+	record := []byte{recordTag}
+	record = binary.AppendUvarint(record, uint64(len(key)))
+	record = append(record, key...)
+	record = binary.AppendUvarint(record, uint64(len(value)))
+	record = append(record, value...)
+	rw.sum.Write(record)
+	rw.manifest.Records++
+	rw.manifest.Namespaces[namespaceOf(key)]++
+	_, err := rw.w.Write(record)
+	return err
+}
+
+func (rw *recordWriter) Finish() (Manifest, error) {
+	rw.manifest.SHA256 = hex.EncodeToString(rw.sum.Sum(nil))
+	data, err := json.Marshal(rw.manifest)
+	if err != nil {
+		return rw.manifest, err
+	}
+	out := []byte{manifestTag}
+	out = binary.AppendUvarint(out, uint64(len(data)))
+	out = append(out, data...)
+	_, err = rw.w.Write(out)
+	return rw.manifest, err
+}
+
+// readRecords gọi fn cho từng record rồi kiểm tra manifest; fn nil chỉ để kiểm tra file
+func readRecords(r io.Reader, fn func(key []byte, value []byte) error) (Manifest, error) {
+	var manifest Manifest
+	br := bufio.NewReader(r)
+	sum := sha256.New()
+	records := 0
+	for {
+		tag, err := br.ReadByte()
+		if err != nil {
+			return manifest, unexpected(err)
+		}
+		switch tag {
+		case recordTag:
+			key, err := readField(br)
+			if err != nil {
+				return manifest, err
+			}
+			value, err := readField(br)
+			if err != nil {
+				return manifest, err
+			}
+			record := []byte{recordTag}
+			record = binary.AppendUvarint(record, uint64(len(key)))
+			record = append(record, key...)
+			record = binary.AppendUvarint(record, uint64(len(value)))
+			record = append(record, value...)
+			sum.Write(record)
+			records++
+			if fn != nil {
+				if err := fn(key, value); err != nil {
+					return manifest, err
+				}
+			}
+		case manifestTag:
+			data, err := readField(br)
+			if err != nil {
+				return manifest, err
+			}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return manifest, fmt.Errorf("backup: invalid manifest: %w", err)
+			}
+			if _, err := br.ReadByte(); err != io.EOF {
+				return manifest, errors.New("backup: data after manifest")
+			}
+			if manifest.Records != records || manifest.SHA256 != hex.EncodeToString(sum.Sum(nil)) {
+				return manifest, ErrChecksum
+			}
+			return manifest, nil
+		default:
+			return manifest, fmt.Errorf("backup: unknown record tag %q", tag)
+		}
+	}
+}
+
+func readField(br *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if n > 64*1024*1024 {
+		return nil, errors.New("backup: record too large")
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(br, data); err != nil {
+		return nil, unexpected(err)
+	}
+	return data, nil
+}
+
+// unexpected đổi EOF giữa chừng (thiếu manifest) thành ErrTruncated
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	// "github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/viper"
@@ -20,6 +22,8 @@ type AppConfig struct {
 
 	CardAddress string
 	CardABIPath string
+	// Danh sách deployment của card contract; để trống thì dùng CardAddress/CardABIPath ở trên
+	CardContracts []CardContractConfig
 
 	ServerPrivateKeyPath     string
 	ParentConnectionAddress  string
@@ -30,14 +34,246 @@ type AppConfig struct {
 	AdminAddress string
 	PathLevelDB string
 	ThirdPartyApiUrl string
+	// MID gửi khi truy vấn trạng thái giao dịch chưa có merchant trong registry (charge tạo trước khi có registry)
+	DefaultMID string
 	StoredPubKey string
 	RpcURL string
+	// Backend gửi transaction: "metanode" (mặc định) hoặc "ethereum" (ethclient qua RpcURL, ký bằng EthPrivateKey)
+	ChainBackend string
+	// Private key ECDSA dạng hex của sender khi ChainBackend là ethereum
+	EthPrivateKey string
+	// Endpoint của acquirer nhận OTP cho giao dịch cần xác thực chủ thẻ
+	ChallengeApiUrl string
+	// Secret HMAC-SHA256 dùng để xác thực kết quả challenge gửi tới /api/v1/challenges/:txId/result
+	ChallengeSecret string
+	// Secret HMAC-SHA256 dùng để xác thực webhook của acquirer
+	WebhookSecret string
+	// Key cho header X-Admin-Key của các API /api/v1/admin, để trống là tắt
+	AdminApiKey string
+	// Chu kỳ retry các settlement saga chưa hoàn tất
+	SettlementRetryInterval time.Duration
+
+	// Quy đổi số tiền on-chain sang minor unit của acquirer
+	ChargeCurrency         string
+	ChargeCurrencyExponent int32
+	TokenDecimals          int32
+
+	Risk       RiskConfig
+	AutoLock   AutoLockConfig
+	Reconcile  ReconcileConfig
+	CleanUsage CleanUsageConfig
+	// Gas, phí, thời gian chờ và số lần chờ receipt theo method của card contract
+	Transactions TransactionsConfig
+	StatusBatch  StatusBatchConfig
+	Storage      StorageConfig
+	Backup       BackupConfig
+}
+
+// TxPolicy là chính sách gửi transaction của một method; trường để 0 lấy theo Default
+type TxPolicy struct {
+	MaxGas      uint64
+	MaxGasPrice uint64
+	TimeUse     uint64
+	Timeout     time.Duration
+	// Số cửa sổ Timeout chờ receipt; 0 là dùng giá trị mặc định của từng method trong code
+	Attempts int
+	// Ước lượng gas bằng eth_estimateGas qua RpcURL, gas limit = ước lượng * GasMultiplier (không vượt MaxGas)
+	EstimateGas bool
+}
+
+// TransactionsConfig gom policy mặc định và policy riêng theo tên method trong ABI
+type TransactionsConfig struct {
+	Default       TxPolicy
+	Methods       map[string]TxPolicy
+	GasMultiplier float64
+}
+
+// Policy trả về policy của method, key trong Methods không phân biệt hoa thường
+// (viper đưa mọi key về chữ thường)
+func (c TransactionsConfig) Policy(method string) TxPolicy {
+	p := c.Default
+	m, ok := c.Methods[strings.ToLower(method)]
+	if !ok {
+		return p
+	}
+	if m.MaxGas > 0 {
+		p.MaxGas = m.MaxGas
+	}
+	if m.MaxGasPrice > 0 {
+		p.MaxGasPrice = m.MaxGasPrice
+	}
+	if m.TimeUse > 0 {
+		p.TimeUse = m.TimeUse
+	}
+	if m.Timeout > 0 {
+		p.Timeout = m.Timeout
+	}
+	if m.Attempts > 0 {
+		p.Attempts = m.Attempts
+	}
+	p.EstimateGas = p.EstimateGas || m.EstimateGas
+	return p
+}
+
+// CardContractConfig là một deployment của card contract mà service quản lý
+type CardContractConfig struct {
+	Name    string
+	Address string
+	ABIPath string
+	// ChainId và RpcURL để trống thì lấy theo cấu hình chung
+	ChainId uint64
+	RpcURL  string
+	// Block bắt đầu quét event khi deployment chưa có cursor; 0 là bắt đầu từ block mới nhất
+	StartBlock uint64
+	// Khẳng định contract đã deploy có batchUpdateTxStatus; chỉ cần khi không có RpcURL để
+	// probe bằng eth_call, có RpcURL thì kết quả probe được dùng
+	StatusBatch bool
+}
+
+// Giá trị của ChainBackend
+const (
+	BackendMetaNode = "metanode"
+	BackendEthereum = "ethereum"
+)
+
+// EthereumBackend cho biết transaction được gửi qua Ethereum JSON-RPC thay vì meta-node
+func (c *AppConfig) EthereumBackend() bool {
+	return c.ChainBackend == BackendEthereum
+}
+
+// DefaultContractName là tên deployment dựng từ CardAddress/CardABIPath cũ,
+// cursor của nó giữ key "lastBlock" như trước
+const DefaultContractName = "default"
+
+// Contracts trả về các deployment đã điền ChainId/RpcURL mặc định
+func (c *AppConfig) Contracts() []CardContractConfig {
+	if len(c.CardContracts) == 0 {
+		return []CardContractConfig{{
+			Name:    DefaultContractName,
+			Address: c.CardAddress,
+			ABIPath: c.CardABIPath,
+			ChainId: c.ChainId,
+			RpcURL:  c.RpcURL,
+		}}
+	}
+	contracts := make([]CardContractConfig, len(c.CardContracts))
+	for i, contract := range c.CardContracts {
+		if contract.ChainId == 0 {
+			contract.ChainId = c.ChainId
+		}
+		if contract.RpcURL == "" {
+			contract.RpcURL = c.RpcURL
+		}
+		contracts[i] = contract
+	}
+	return contracts
+}
+
+// CleanUsageConfig cấu hình job gọi cleanUsage định kỳ để dọn lịch sử usage trên contract
+type CleanUsageConfig struct {
+	Enabled  bool
+	Interval time.Duration
+	// Chỉ xoá usage cũ hơn Retention; phải đủ dài cho giới hạn theo tuần của contract
+	Retention time.Duration
+	// Mỗi transaction chỉ dời beforeTimestamp thêm BatchWindow để không vượt gas limit
+	BatchWindow time.Duration
+	MaxBatches  int
+}
+
+// Giá trị của Storage.Backend
+const (
+	StorageLevelDB = "leveldb"
+	StorageBadger  = "badger"
+	StorageSQL     = "sql"
+)
+
+// StorageConfig chọn backend lưu token, cursor và ledger charge
+type StorageConfig struct {
+	// "leveldb" (mặc định, thư mục PathLevelDB), "badger" hoặc "sql"
+	Backend string
+	// Thư mục dữ liệu khi Backend là badger
+	Path string
+	// Driver SQL: "sqlite" (DSN là đường dẫn file), "mysql" hoặc "postgres"
+	Driver string
+	// DSN của SQL; driver mysql để trống thì dùng MYSQL_URL
+	DSN string
+}
+
+// BackupConfig cấu hình snapshot mã hoá của store (lệnh backup và POST /admin/snapshots)
+type BackupConfig struct {
+	// Thư mục chứa snapshot tạo qua admin API
+	Dir string
+	// File chứa passphrase mã hoá snapshot, không để chung thư mục với Dir
+	KeyFile string
+	// Số snapshot giữ lại trong Dir, 0 là giữ hết
+	Keep int
+}
+
+// StatusBatchConfig gộp các lời gọi UpdateTxStatus trong Window thành một transaction
+// batchUpdateTxStatus. Batch chỉ bật cho deployment mà contract trên chain có method này:
+// probe bằng eth_call qua RpcURL, hoặc CardContractConfig.StatusBatch khi không có RpcURL;
+// các deployment còn lại gửi từng lời gọi như cũ
+type StatusBatchConfig struct {
+	Enabled bool
+	Window  time.Duration
+	MaxSize int
+}
+
+// ReconcileConfig cấu hình job đối chiếu file settlement của acquirer
+type ReconcileConfig struct {
+	Enabled    bool
+	InboxDir   string
+	ReportDir  string
+	Interval   time.Duration
+	AutoRepair bool
+	// Giao dịch BEING_PROCESSED lâu hơn StuckAfter được báo là bị kẹt
+	StuckAfter time.Duration
+}
+
+// AutoLockConfig cấu hình tự động khoá thẻ/token khi acquirer từ chối liên tục
+type AutoLockConfig struct {
+	Enabled bool
+	// Số lần bị từ chối liên tiếp để khoá thẻ (theo cardHash) hoặc tắt token; 0 là không dùng
+	MaxCardDeclines  int
+	MaxTokenDeclines int
+	// Lý do từ chối (so khớp chuỗi con, không phân biệt hoa thường) khiến thẻ bị khoá ngay
+	LockReasons []string
+}
+
+// RiskConfig cấu hình risk engine chạy trước khi gửi charge sang acquirer
+type RiskConfig struct {
+	Enabled            bool
+	ReviewAmountAbove  int64
+	DeclineAmountAbove int64
+	NewTokenCooldown   time.Duration
+	BlockedCategories  []string
+	ReviewCategories   []string
+	CardVelocityLimit  int
+	CardVelocityWindow time.Duration
 }
 
 var Config *AppConfig
 
 func LoadConfig(path string) (*AppConfig, error) {
 	viper.SetConfigFile(path)
+	viper.SetDefault("ChargeCurrency", "VND")
+	viper.SetDefault("ChargeCurrencyExponent", 0)
+	viper.SetDefault("TokenDecimals", 0)
+	viper.SetDefault("DefaultMID", "pos123")
+	viper.SetDefault("Transactions.Default.MaxGas", 5_000_000)
+	viper.SetDefault("Transactions.Default.MaxGasPrice", 1_000_000_000)
+	viper.SetDefault("Transactions.Default.Timeout", "60s")
+	viper.SetDefault("Transactions.GasMultiplier", 1.2)
+	viper.SetDefault("CleanUsage.Interval", "24h")
+	viper.SetDefault("CleanUsage.Retention", "336h")
+	viper.SetDefault("CleanUsage.BatchWindow", "6h")
+	viper.SetDefault("CleanUsage.MaxBatches", 20)
+	viper.SetDefault("ChainBackend", BackendMetaNode)
+	viper.SetDefault("StatusBatch.Window", "200ms")
+	viper.SetDefault("StatusBatch.MaxSize", 50)
+	viper.SetDefault("Storage.Backend", StorageLevelDB)
+	viper.SetDefault("Backup.Dir", "backups")
+	viper.SetDefault("Backup.Keep", 7)
 
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
@@ -47,6 +283,52 @@ func LoadConfig(path string) (*AppConfig, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config:   %w", err)
 	}
+	if config.ChargeCurrencyExponent < 0 || config.TokenDecimals < 0 {
+		return nil, fmt.Errorf("ChargeCurrencyExponent and TokenDecimals must not be negative")
+	}
+	names := map[string]bool{}
+	for _, contract := range config.CardContracts {
+		if contract.Name == "" || contract.Address == "" || contract.ABIPath == "" {
+			return nil, fmt.Errorf("CardContracts entries need Name, Address and ABIPath")
+		}
+		if names[contract.Name] {
+			return nil, fmt.Errorf("duplicate CardContracts name %q", contract.Name)
+		}
+		names[contract.Name] = true
+	}
+	switch config.ChainBackend {
+	case BackendMetaNode:
+	case BackendEthereum:
+		if config.EthPrivateKey == "" {
+			return nil, fmt.Errorf("EthPrivateKey is required when ChainBackend is %q", BackendEthereum)
+		}
+		for _, contract := range config.Contracts() {
+			if contract.RpcURL == "" {
+				return nil, fmt.Errorf("RpcURL is required for contract %q when ChainBackend is %q", contract.Name, BackendEthereum)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown ChainBackend %q", config.ChainBackend)
+	}
+	switch config.Storage.Backend {
+	case StorageLevelDB:
+	case StorageBadger:
+		if config.Storage.Path == "" {
+			return nil, fmt.Errorf("Storage.Path is required when Storage.Backend is %q", StorageBadger)
+		}
+	case StorageSQL:
+		if config.Storage.DSN == "" && config.Storage.Driver == "mysql" {
+			config.Storage.DSN = config.MYSQL_URL
+		}
+		if config.Storage.Driver == "" || config.Storage.DSN == "" {
+			return nil, fmt.Errorf("Storage.Driver and Storage.DSN are required when Storage.Backend is %q", StorageSQL)
+		}
+	default:
+		return nil, fmt.Errorf("unknown Storage.Backend %q", config.Storage.Backend)
+	}
+	if config.CleanUsage.Enabled && config.CleanUsage.Retention < 7*24*time.Hour {
+		return nil, fmt.Errorf("CleanUsage.Retention must be at least 168h to keep weekly usage limits correct")
+	}
 
 	Config = &config
 	return &config, nil
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v4"
+)
+
+const (
+	badgerGCInterval = 10 * time.Minute
+	// Số lần chạy lại Update khi transaction badger bị xung đột với transaction khác
+	badgerConflictRetries = 3
+)
+
+// OpenBadger mở backend Badger tại thư mục path và chạy GC value log định kỳ
+func OpenBadger(path string) (KV, error) {
+	db, err := badger.Open(badger.DefaultOptions(path).WithLogger(nil))
+	if err != nil {
+		return nil, err
+	}
+	kv := &badgerKV{db: db, stop: make(chan struct{})}
+	go kv.runGC()
+	return kv, nil
+}
+
+type badgerKV struct {
+	db        *badger.DB
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+func (b *badgerKV) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := b.db.View(func(txn *badger.Txn) error {
+		var err error
+		value, err = (&badgerBucket{txn}).Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (b *badgerKV) Put(key []byte, value []byte) error {
+	return b.Update(func(txn Bucket) error {
+		return txn.Put(key, value)
+	})
+}
+
+func (b *badgerKV) Delete(key []byte) error {
+	return b.Update(func(txn Bucket) error {
+		return txn.Delete(key)
+	})
+}
+
+func (b *badgerKV) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return (&badgerBucket{txn}).Iterate(prefix, fn)
+	})
+}
+
+// Update dùng transaction của badger nên Iterate trong txn cũng thấy ghi chưa commit
+func (b *badgerKV) Update(fn func(txn Bucket) error) error {
+	var err error
+	for i := 0; i < badgerConflictRetries; i++ {
+		err = b.db.Update(func(txn *badger.Txn) error {
+			return fn(&badgerBucket{txn})
+		})
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+	return err
+}
+
+// View dùng transaction chỉ đọc của badger, vốn đọc trên một phiên bản cố định
+func (b *badgerKV) View(fn func(view Reader) error) error {
+	return b.db.View(func(txn *badger.Txn) error {
+		return fn(&badgerBucket{txn})
+	})
+}
+
+func (b *badgerKV) Close() error {
+	b.closeOnce.Do(func() { close(b.stop) })
+	return b.db.Close()
+}
+
+func (b *badgerKV) runGC() {
+	ticker := time.NewTicker(badgerGCInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			// RunValueLogGC trả lỗi khi không còn gì để dọn
+			for b.db.RunValueLogGC(0.5) == nil {
+			}
+		}
+	}
+}
+
+type badgerBucket struct {
+	txn *badger.Txn
+}
+
+func (b *badgerBucket) Get(key []byte) ([]byte, error) {
+	item, err := b.txn.Get(key)
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return item.ValueCopy(nil)
+}
+
+func (b *badgerBucket) Put(key []byte, value []byte) error {
+	return b.txn.Set(append([]byte{}, key...), append([]byte{}, value...))
+}
+
+func (b *badgerBucket) Delete(key []byte) error {
+	return b.txn.Delete(append([]byte{}, key...))
+}
+
+func (b *badgerBucket) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	opts := badger.DefaultIteratorOptions
+	opts.Prefix = prefix
+	it := b.txn.NewIterator(opts)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		err := item.Value(func(value []byte) error {
+			return fn(item.Key(), value)
+		})
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const challengePrefix = "challenge_"
+
+var ErrChallengeNotFound = errors.New("challenge not found")
+
+type ChallengeRepo struct {
+	s *store
+}
+
+func (r *ChallengeRepo) Save(c model.Challenge) error {
+	return putJSON(Namespace(r.s.bucket(), challengePrefix), c.TxID, c)
+}
+
+func (r *ChallengeRepo) Get(txID string) (model.Challenge, error) {
+	var c model.Challenge
+	err := getJSON(Namespace(r.s.bucket(), challengePrefix), txID, &c, ErrChallengeNotFound)
+	return c, err
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const (
+	chargePrefix      = "charge_"
+	chargeIndexPrefix = "chargeidx_"
+	heldChargePrefix  = "held_"
+)
+
+// Các index phụ của ledger charge.
+const (
+	ChargeByToken    = "token"
+	ChargeByMerchant = "merchant"
+	ChargeByStatus   = "status"
+)
+
+var (
+	ErrChargeNotFound     = errors.New("charge not found")
+	ErrHeldChargeNotFound = errors.New("held charge not found")
+)
+
+// ChargeRepo là ledger charge cùng các charge bị risk engine giữ lại
+type ChargeRepo struct {
+	s *store
+}
+
+func chargeIndexKey(index string, value string, txID string) []byte {
+	return []byte(index + "_" + strings.ToLower(value) + "_" + txID)
+}
+
+// Save ghi charge cùng các index token/merchant/status trong một transaction
+func (r *ChargeRepo) Save(charge model.Charge) error {
+	return r.s.update(func(b Bucket) error {
+		records := Namespace(b, chargePrefix)
+		index := Namespace(b, chargeIndexPrefix)
+		var old model.Charge
+		if err := getJSON(records, charge.TxID, &old, ErrChargeNotFound); err == nil && old.Status != charge.Status {
+			if err := index.Delete(chargeIndexKey(ChargeByStatus, old.Status, charge.TxID)); err != nil {
+				return err
+			}
+		}
+		if err := putJSON(records, charge.TxID, charge); err != nil {
+			return err
+		}
+		for _, key := range [][]byte{
+			chargeIndexKey(ChargeByToken, charge.TokenID, charge.TxID),
+			chargeIndexKey(ChargeByMerchant, charge.Merchant, charge.TxID),
+			chargeIndexKey(ChargeByStatus, charge.Status, charge.TxID),
+		} {
+			if err := index.Put(key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *ChargeRepo) Get(txID string) (model.Charge, error) {
+	var charge model.Charge
+	err := getJSON(Namespace(r.s.bucket(), chargePrefix), txID, &charge, ErrChargeNotFound)
+	return charge, err
+}
+
+func (r *ChargeRepo) List() ([]model.Charge, error) {
+	charges := []model.Charge{}
+	err := iterateJSON(Namespace(r.s.bucket(), chargePrefix), func(charge model.Charge) error {
+		charges = append(charges, charge)
+		return nil
+	})
+	return charges, err
+}
+
+// ListBy trả về tối đa limit charge theo index (ChargeByToken, ChargeByMerchant, ChargeByStatus); limit <= 0 là không giới hạn
+func (r *ChargeRepo) ListBy(index string, value string, limit int) ([]model.Charge, error) {
+	prefix := []byte(index + "_" + strings.ToLower(value) + "_")
+	charges := []model.Charge{}
+	err := Namespace(r.s.bucket(), chargeIndexPrefix).Iterate(prefix, func(key []byte, _ []byte) error {
+		if limit > 0 && len(charges) >= limit {
+			return ErrStopIteration
+		}
+		charge, err := r.Get(string(key[len(prefix):]))
+		if err != nil {
+			return err
+		}
+		charges = append(charges, charge)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return charges, nil
+}
+
+func (r *ChargeRepo) SaveHeld(held model.HeldCharge) error {
+	return putJSON(Namespace(r.s.bucket(), heldChargePrefix), held.TxID, held)
+}
+
+func (r *ChargeRepo) GetHeld(txID string) (model.HeldCharge, error) {
+	var held model.HeldCharge
+	err := getJSON(Namespace(r.s.bucket(), heldChargePrefix), txID, &held, ErrHeldChargeNotFound)
+	return held, err
+}
+
+func (r *ChargeRepo) ListHeld() ([]model.HeldCharge, error) {
+	charges := []model.HeldCharge{}
+	err := iterateJSON(Namespace(r.s.bucket(), heldChargePrefix), func(held model.HeldCharge) error {
+		charges = append(charges, held)
+		return nil
+	})
+	return charges, err
+}
+
+func (r *ChargeRepo) DeleteHeld(txID string) error {
+	return Namespace(r.s.bucket(), heldChargePrefix).Delete([]byte(txID))
+}
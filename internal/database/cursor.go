@@ -0,0 +1,41 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+)
+
+const cursorPrefix = "lastBlock"
+
+// CursorRepo lưu block cuối listener đã quét theo deployment
+type CursorRepo struct {
+	s *store
+}
+
+// cursorKey giữ key cũ: "lastBlock" cho deployment mặc định (name rỗng), "lastBlock_<name>" cho các deployment khác
+func cursorKey(name string) []byte {
+	if name == "" {
+		return nil
+	}
+	return []byte("_" + name)
+}
+
+// Get trả về block đã quét của deployment, ok=false nếu chưa có cursor
+func (r *CursorRepo) Get(name string) (uint64, bool, error) {
+	data, err := Namespace(r.s.bucket(), cursorPrefix).Get(cursorKey(name))
+	if errors.Is(err, ErrNotFound) || (err == nil && len(data) == 0) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	block, err := strconv.ParseUint(string(data), 0, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return block, true, nil
+}
+
+func (r *CursorRepo) Set(name string, block uint64) error {
+	return Namespace(r.s.bucket(), cursorPrefix).Put(cursorKey(name), []byte(strconv.FormatUint(block, 10)))
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const (
+	declinePrefix   = "declines_"
+	lockAuditPrefix = "lockaudit_"
+)
+
+var errStatsNotFound = errors.New("decline stats not found")
+
+// DeclineRepo lưu bộ đếm decline của auto-lock và lịch sử khoá/mở khoá
+type DeclineRepo struct {
+	s *store
+}
+
+// Stats trả về bộ đếm của key (vd "card_<hash>", "token_<id>"), rỗng nếu chưa có.
+func (r *DeclineRepo) Stats(key string) (model.DeclineStats, error) {
+	var stats model.DeclineStats
+	err := getJSON(Namespace(r.s.bucket(), declinePrefix), key, &stats, errStatsNotFound)
+	if errors.Is(err, errStatsNotFound) {
+		return model.DeclineStats{}, nil
+	}
+	return stats, err
+}
+
+func (r *DeclineRepo) SaveStats(key string, stats model.DeclineStats) error {
+	return putJSON(Namespace(r.s.bucket(), declinePrefix), key, stats)
+}
+
+func (r *DeclineRepo) SaveLockAudit(audit model.LockAudit) error {
+	key := fmt.Sprintf("%020d_%s_%s%s", audit.At, audit.Action, audit.CardHash, audit.TokenID)
+	return putJSON(Namespace(r.s.bucket(), lockAuditPrefix), key, audit)
+}
+
+func (r *DeclineRepo) ListLockAudits() ([]model.LockAudit, error) {
+	audits := []model.LockAudit{}
+	err := iterateJSON(Namespace(r.s.bucket(), lockAuditPrefix), func(audit model.LockAudit) error {
+		audits = append(audits, audit)
+		return nil
+	})
+	return audits, err
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const eventPrefix = "event_"
+
+// EventRepo ghi lại các event log đã được handler xử lý xong, để lần quét lại sau khi
+// restart không xử lý một event hai lần
+type EventRepo struct {
+	s *store
+}
+
+// eventKey sắp theo block để Prune xoá theo khoảng: <block 20 chữ số>_<txHash>_<logIndex>
+func eventKey(log model.EventLog) ([]byte, error) {
+	block, err := strconv.ParseUint(log.BlockNumber, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blockNumber %q: %w", log.BlockNumber, err)
+	}
+	logIndex, err := strconv.ParseUint(log.LogIndex, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logIndex %q: %w", log.LogIndex, err)
+	}
+	return []byte(fmt.Sprintf("%020d_%s_%d", block, strings.ToLower(log.TransactionHash), logIndex)), nil
+}
+
+// Seen cho biết event đã được xử lý xong trước đó chưa
+func (r *EventRepo) Seen(log model.EventLog) (bool, error) {
+	key, err := eventKey(log)
+	if err != nil {
+		return false, err
+	}
+	_, err = Namespace(r.s.bucket(), eventPrefix).Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// MarkSeen ghi event sau khi handler đã xử lý xong, lần quét lại sẽ bỏ qua event này
+func (r *EventRepo) MarkSeen(log model.EventLog) error {
+	key, err := eventKey(log)
+	if err != nil {
+		return err
+	}
+	return r.s.update(func(b Bucket) error {
+		return putJSON(Namespace(b, eventPrefix), string(key), log)
+	})
+}
+
+// Prune xoá các event ở block nhỏ hơn beforeBlock và trả về số event đã xoá
+func (r *EventRepo) Prune(beforeBlock uint64) (int, error) {
+	limit := fmt.Sprintf("%020d", beforeBlock)
+	removed := 0
+	err := r.s.update(func(b Bucket) error {
+		events := Namespace(b, eventPrefix)
+		var keys [][]byte
+		err := events.Iterate(nil, func(key []byte, _ []byte) error {
+			if string(key) >= limit {
+				return ErrStopIteration
+			}
+			keys = append(keys, append([]byte{}, key...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if err := events.Delete(key); err != nil {
+				return err
+			}
+		}
+		removed = len(keys)
+		return nil
+	})
+	return removed, err
+}
@@ -2,61 +2,129 @@ package database
 
 import (
 	"errors"
-	"fmt"
-	"log"
+	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
-func Open( path string) (*leveldb.DB,error){
+
+// Open mở Store trên LevelDB tại path
+func Open(path string) (Store, error) {
+	kv, err := OpenLevelDB(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewStore(kv), nil
+}
+
+// OpenLevelDB mở backend LevelDB, chỉ một process được mở một thư mục tại một thời điểm
+func OpenLevelDB(path string) (KV, error) {
 	db, err := leveldb.OpenFile(path, nil)
-    if err != nil {
-        return nil, err
-    }
-    // defer db.Close()
+	if err != nil {
+		return nil, err
+	}
 	if db == nil {
-        return nil, errors.New("leveldb connection is nil after opening")
-    }
-    return db, nil}
-func WriteValueStorage(call map[string]interface{},db *leveldb.DB) error {
-	key, ok := call["key"].(string)
-	data, ok := call["data"].(string)
-	if !ok || key == "" || data == "" {
-		return fmt.Errorf("invalid key or data")
-	}
-
-	// securyDb.Write(key, data)
-	err := db.Put([]byte(key), []byte(data), nil)
-	if err!=nil{
-		return err
+		return nil, errors.New("leveldb connection is nil after opening")
 	}
-	return nil
+	return &levelKV{db: db}, nil
+}
+
+type levelKV struct {
+	db *leveldb.DB
+	// update cho các Update chạy lần lượt: fn đọc rồi mới ghi batch, hai Update song song
+	// có thể cùng đọc giá trị cũ và ghi đè nhau
+	update sync.Mutex
 }
- func ReadValueStorage(call map[string]interface{},db *leveldb.DB) ([]byte,error ){
-	key, ok := call["key"].(string)
-	if !ok || key == "" {
-		return nil,fmt.Errorf("Key not found")
+
+func (l *levelKV) Get(key []byte) ([]byte, error) {
+	value, err := l.db.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, ErrNotFound
 	}
-	value,err := db.Get([]byte(key), nil)
-	if err != nil {
-		return nil,err
-	
-	} 
-	return value,nil
+	return value, err
+}
+
+func (l *levelKV) Put(key []byte, value []byte) error {
+	return l.db.Put(key, value, nil)
+}
+
+func (l *levelKV) Delete(key []byte) error {
+	return l.db.Delete(key, nil)
+}
+
+func (l *levelKV) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	iter := l.db.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return iter.Error()
 }
-func DeleteKeyStorage(call map[string]interface{},db *leveldb.DB) map[string]interface{} {
-	key, ok := call["key"].(string)
-	if !ok || key == "" {
-	return map[string]interface{}{
-	"error": "EDKS-001",
-	"message": "Key is required",
+
+// Update ghi các thay đổi của txn bằng một leveldb.Batch. Các Update được tuần tự hoá nên
+// đọc-sửa-ghi trong fn không bị Update khác chen vào; Put/Delete lẻ không đi qua khoá này.
+func (l *levelKV) Update(fn func(txn Bucket) error) error {
+	l.update.Lock()
+	defer l.update.Unlock()
+	txn := newWriteTxn(l)
+	if err := fn(txn); err != nil {
+		return err
+	}
+	if len(txn.ops) == 0 {
+		return nil
+	}
+	batch := new(leveldb.Batch)
+	for _, op := range txn.ops {
+		if op.delete {
+			batch.Delete(op.key)
+		} else {
+			batch.Put(op.key, op.value)
+		}
 	}
+	return l.db.Write(batch, nil)
+}
+
+// View đọc qua leveldb.Snapshot
+func (l *levelKV) View(fn func(view Reader) error) error {
+	snapshot, err := l.db.GetSnapshot()
+	if err != nil {
+		return err
 	}
-	// securyDb.Delete(key)
-	err := db.Delete([]byte(key), nil)
-	if err!=nil{
-		log.Fatal()
+	defer snapshot.Release()
+	return fn(&levelSnapshot{snapshot})
+}
+
+func (l *levelKV) Close() error {
+	return l.db.Close()
+}
+
+type levelSnapshot struct {
+	snapshot *leveldb.Snapshot
+}
+
+func (l *levelSnapshot) Get(key []byte) ([]byte, error) {
+	value, err := l.snapshot.Get(key, nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return nil, ErrNotFound
 	}
-	return map[string]interface{}{
-	"success": true,
+	return value, err
+}
+
+func (l *levelSnapshot) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	iter := l.snapshot.NewIterator(util.BytesPrefix(prefix), nil)
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
 	}
-}
\ No newline at end of file
+	return iter.Error()
+}
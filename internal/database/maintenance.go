@@ -0,0 +1,29 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const cleanUsageStatePrefix = "maintenance_cleanusage_"
+
+var errStateNotFound = errors.New("maintenance state not found")
+
+type MaintenanceRepo struct {
+	s *store
+}
+
+// CleanUsageState trả về state của deployment contract, rỗng nếu job chưa chạy lần nào
+func (r *MaintenanceRepo) CleanUsageState(contract string) (model.CleanUsageState, error) {
+	var state model.CleanUsageState
+	err := getJSON(Namespace(r.s.bucket(), cleanUsageStatePrefix), contract, &state, errStateNotFound)
+	if errors.Is(err, errStateNotFound) {
+		return model.CleanUsageState{}, nil
+	}
+	return state, err
+}
+
+func (r *MaintenanceRepo) SaveCleanUsageState(contract string, state model.CleanUsageState) error {
+	return putJSON(Namespace(r.s.bucket(), cleanUsageStatePrefix), contract, state)
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OpenMemory tạo Store trong bộ nhớ, dùng cho test và e2e; dữ liệu mất khi Close
+func OpenMemory() Store {
+	return NewStore(NewMemoryKV())
+}
+
+// NewMemoryKV tạo backend KV trong bộ nhớ
+func NewMemoryKV() KV {
+	return &memKV{data: make(map[string][]byte)}
+}
+
+type memKV struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+	// update tuần tự hoá các Update giống levelKV
+	update sync.Mutex
+}
+
+func (m *memKV) Get(key []byte) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.data[string(key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (m *memKV) Put(key []byte, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[string(key)] = append([]byte{}, value...)
+	return nil
+}
+
+func (m *memKV) Delete(key []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.data, string(key))
+	return nil
+}
+
+// Iterate duyệt trên bản chụp các key có prefix nên fn được phép ghi vào KV
+func (m *memKV) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	m.mu.RLock()
+	keys := make([]string, 0)
+	for key := range m.data {
+		if strings.HasPrefix(key, string(prefix)) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = m.data[key]
+	}
+	m.mu.RUnlock()
+
+	for i, key := range keys {
+		if err := fn([]byte(key), values[i]); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memKV) Update(fn func(txn Bucket) error) error {
+	m.update.Lock()
+	defer m.update.Unlock()
+	txn := newWriteTxn(m)
+	if err := fn(txn); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, op := range txn.ops {
+		if op.delete {
+			delete(m.data, string(op.key))
+		} else {
+			m.data[string(op.key)] = op.value
+		}
+	}
+	return nil
+}
+
+// View đọc trên bản sao của toàn bộ dữ liệu
+func (m *memKV) View(fn func(view Reader) error) error {
+	m.mu.RLock()
+	snapshot := &memKV{data: make(map[string][]byte, len(m.data))}
+	for key, value := range m.data {
+		snapshot.data[key] = value
+	}
+	m.mu.RUnlock()
+	return fn(snapshot)
+}
+
+func (m *memKV) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const merchantPrefix = "merchant_"
+
+var ErrMerchantNotFound = errors.New("merchant not registered")
+
+type MerchantRepo struct {
+	s *store
+}
+
+func merchantKey(address common.Address) string {
+	return strings.ToLower(address.Hex())
+}
+
+func (r *MerchantRepo) Save(m model.Merchant) error {
+	if !common.IsHexAddress(m.Address) {
+		return fmt.Errorf("invalid merchant address %q", m.Address)
+	}
+	if m.MID == "" {
+		return fmt.Errorf("mId is required")
+	}
+	address := common.HexToAddress(m.Address)
+	m.Address = address.Hex()
+	return putJSON(Namespace(r.s.bucket(), merchantPrefix), merchantKey(address), m)
+}
+
+func (r *MerchantRepo) Get(address common.Address) (model.Merchant, error) {
+	var m model.Merchant
+	err := getJSON(Namespace(r.s.bucket(), merchantPrefix), merchantKey(address), &m, ErrMerchantNotFound)
+	return m, err
+}
+
+func (r *MerchantRepo) List() ([]model.Merchant, error) {
+	merchants := []model.Merchant{}
+	err := iterateJSON(Namespace(r.s.bucket(), merchantPrefix), func(m model.Merchant) error {
+		merchants = append(merchants, m)
+		return nil
+	})
+	return merchants, err
+}
+
+func (r *MerchantRepo) Delete(address common.Address) error {
+	return Namespace(r.s.bucket(), merchantPrefix).Delete([]byte(merchantKey(address)))
+}
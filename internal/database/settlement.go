@@ -0,0 +1,47 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const settlementPrefix = "settlement_"
+
+var ErrSettlementNotFound = errors.New("settlement not found")
+
+type SettlementRepo struct {
+	s *store
+}
+
+func (r *SettlementRepo) Save(s model.Settlement) error {
+	return putJSON(Namespace(r.s.bucket(), settlementPrefix), s.TxID, s)
+}
+
+func (r *SettlementRepo) Get(txID string) (model.Settlement, error) {
+	var s model.Settlement
+	err := getJSON(Namespace(r.s.bucket(), settlementPrefix), txID, &s, ErrSettlementNotFound)
+	return s, err
+}
+
+// ListPending trả về các saga chưa hoàn tất và không bị dừng chờ xử lý tay
+func (r *SettlementRepo) ListPending() ([]model.Settlement, error) {
+	pending := []model.Settlement{}
+	err := iterateJSON(Namespace(r.s.bucket(), settlementPrefix), func(s model.Settlement) error {
+		if !s.Done() && !s.NeedsReview {
+			pending = append(pending, s)
+		}
+		return nil
+	})
+	return pending, err
+}
+
+// List trả về mọi saga, kể cả saga đã xong hoặc đang chờ xử lý tay
+func (r *SettlementRepo) List() ([]model.Settlement, error) {
+	settlements := []model.Settlement{}
+	err := iterateJSON(Namespace(r.s.bucket(), settlementPrefix), func(s model.Settlement) error {
+		settlements = append(settlements, s)
+		return nil
+	})
+	return settlements, err
+}
@@ -0,0 +1,423 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	_ "modernc.org/sqlite"
+)
+
+// Driver của backend SQL
+const (
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+)
+
+// sqlDialect là phần khác nhau giữa các database; key dùng kiểu nhị phân để so sánh theo byte
+// như LevelDB (collation mặc định của MySQL không phân biệt hoa thường)
+type sqlDialect struct {
+	name     string
+	driver   string
+	keyType  string
+	blobType string
+	// ON CONFLICT (Postgres, SQLite) hay ON DUPLICATE KEY (MySQL)
+	duplicateKey bool
+	numbered     bool
+}
+
+var sqlDialects = map[string]*sqlDialect{
+	DriverSQLite:   {name: DriverSQLite, driver: "sqlite", keyType: "BLOB", blobType: "BLOB"},
+	DriverMySQL:    {name: DriverMySQL, driver: "mysql", keyType: "VARBINARY(255)", blobType: "LONGBLOB", duplicateKey: true},
+	DriverPostgres: {name: DriverPostgres, driver: "pgx", keyType: "BYTEA", blobType: "BYTEA", numbered: true},
+}
+
+// rebind đổi placeholder ? sang $1, $2... cho Postgres
+func (d *sqlDialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+	var out strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			out.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		out.WriteRune(c)
+	}
+	return out.String()
+}
+
+// upsert dựng câu INSERT ghi đè theo khoá chính keyColumn
+func (d *sqlDialect) upsert(table string, keyColumn string, columns []string) string {
+	all := append([]string{keyColumn}, columns...)
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(all)), ", ")
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ", table, strings.Join(all, ", "), marks)
+	sets := make([]string, len(columns))
+	for i, column := range columns {
+		if d.duplicateKey {
+			sets[i] = fmt.Sprintf("%s = VALUES(%s)", column, column)
+		} else {
+			sets[i] = fmt.Sprintf("%s = excluded.%s", column, column)
+		}
+	}
+	if d.duplicateKey {
+		query += "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	} else {
+		query += fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", keyColumn, strings.Join(sets, ", "))
+	}
+	return d.rebind(query)
+}
+
+// sqlMigrations chạy theo thứ tự version và được ghi vào schema_migrations; chỉ thêm migration
+// mới vào cuối, không sửa migration đã phát hành. {{key}} và {{blob}} được thay theo dialect.
+var sqlMigrations = []struct {
+	version    int
+	statements []string
+}{
+	{1, []string{
+		`CREATE TABLE kv (k {{key}} NOT NULL PRIMARY KEY, v {{blob}})`,
+	}},
+	{2, []string{
+		`CREATE TABLE charges (
+			tx_id VARCHAR(128) NOT NULL PRIMARY KEY,
+			contract VARCHAR(64) NOT NULL DEFAULT '',
+			token_id VARCHAR(66) NOT NULL DEFAULT '',
+			user_address VARCHAR(42) NOT NULL DEFAULT '',
+			card_hash VARCHAR(66) NOT NULL DEFAULT '',
+			merchant VARCHAR(42) NOT NULL DEFAULT '',
+			mid VARCHAR(64) NOT NULL DEFAULT '',
+			amount VARCHAR(78) NOT NULL DEFAULT '',
+			money_amount BIGINT NOT NULL DEFAULT 0,
+			currency VARCHAR(8) NOT NULL DEFAULT '',
+			status VARCHAR(16) NOT NULL DEFAULT '',
+			reason TEXT,
+			request_tx_hash VARCHAR(66) NOT NULL DEFAULT '',
+			created_at BIGINT NOT NULL DEFAULT 0,
+			updated_at BIGINT NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX idx_charges_token ON charges (token_id)`,
+		`CREATE INDEX idx_charges_merchant ON charges (merchant)`,
+		`CREATE INDEX idx_charges_status ON charges (status, updated_at)`,
+		`CREATE INDEX idx_charges_created ON charges (created_at)`,
+	}},
+	{3, []string{
+		`CREATE TABLE token_infos (
+			token_id VARCHAR(66) NOT NULL PRIMARY KEY,
+			user_address VARCHAR(42) NOT NULL DEFAULT '',
+			region VARCHAR(16) NOT NULL DEFAULT '',
+			card_hash VARCHAR(66) NOT NULL DEFAULT '',
+			issued_at BIGINT NOT NULL DEFAULT 0,
+			contract VARCHAR(64) NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX idx_token_infos_user ON token_infos (user_address)`,
+		`CREATE INDEX idx_token_infos_card ON token_infos (card_hash)`,
+	}},
+}
+
+// sqlProjection ghi bản ghi của một namespace ra bảng có cột để ops và analytics truy vấn bằng
+// SQL; bảng kv vẫn là nguồn dữ liệu của repository
+type sqlProjection struct {
+	prefix  string
+	table   string
+	key     string
+	columns []string
+	values  func(data []byte) ([]interface{}, error)
+}
+
+var sqlProjections = []sqlProjection{
+	{
+		prefix: chargePrefix,
+		table:  "charges",
+		key:    "tx_id",
+		columns: []string{"contract", "token_id", "user_address", "card_hash", "merchant", "mid", "amount",
+			"money_amount", "currency", "status", "reason", "request_tx_hash", "created_at", "updated_at"},
+		values: func(data []byte) ([]interface{}, error) {
+			var c model.Charge
+			if err := json.Unmarshal(data, &c); err != nil {
+				return nil, err
+			}
+			return []interface{}{c.TxID, c.Contract, c.TokenID, strings.ToLower(c.User), c.CardHash, strings.ToLower(c.Merchant),
+				c.MID, c.Amount, c.Money.Amount, c.Money.Currency, c.Status, c.Reason, c.RequestTxHash, c.CreatedAt, c.UpdatedAt}, nil
+		},
+	},
+	{
+		prefix:  tokenInfoPrefix,
+		table:   "token_infos",
+		key:     "token_id",
+		columns: []string{"user_address", "region", "card_hash", "issued_at", "contract"},
+		values: func(data []byte) ([]interface{}, error) {
+			var t model.TokenInfo
+			if err := json.Unmarshal(data, &t); err != nil {
+				return nil, err
+			}
+			return []interface{}{t.TokenID, strings.ToLower(t.User), t.Region, t.CardHash, t.IssuedAt, t.Contract}, nil
+		},
+	},
+}
+
+func projectionFor(key []byte) *sqlProjection {
+	for i := range sqlProjections {
+		if bytes.HasPrefix(key, []byte(sqlProjections[i].prefix)) {
+			return &sqlProjections[i]
+		}
+	}
+	return nil
+}
+
+// OpenSQL mở backend SQL (driver sqlite, mysql hoặc postgres) và chạy các migration còn thiếu.
+// Với sqlite dsn là đường dẫn file.
+func OpenSQL(driver string, dsn string) (KV, error) {
+	d, ok := sqlDialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown SQL driver %q", driver)
+	}
+	db, err := sql.Open(d.driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if d.name == DriverSQLite {
+		// Một connection để tránh SQLITE_BUSY giữa các connection của cùng process
+		db.SetMaxOpenConns(1)
+		for _, pragma := range []string{"PRAGMA journal_mode = WAL", "PRAGMA busy_timeout = 5000"} {
+			if _, err := db.Exec(pragma); err != nil {
+				db.Close()
+				return nil, err
+			}
+		}
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	kv := &sqlKV{db: db, d: d}
+	if err := kv.migrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	return kv, nil
+}
+
+type sqlKV struct {
+	db *sql.DB
+	d  *sqlDialect
+}
+
+func (s *sqlKV) migrate() error {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER NOT NULL PRIMARY KEY, applied_at BIGINT NOT NULL)`)
+	if err != nil {
+		return err
+	}
+	applied := map[int]bool{}
+	rows, err := s.db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	replacer := strings.NewReplacer("{{key}}", s.d.keyType, "{{blob}}", s.d.blobType)
+	for _, m := range sqlMigrations {
+		if applied[m.version] {
+			continue
+		}
+		// DDL của MySQL tự commit, migration dở dang phải được sửa tay trước khi chạy lại
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, statement := range m.statements {
+			if _, err := tx.Exec(replacer.Replace(statement)); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("version %d: %w", m.version, err)
+			}
+		}
+		_, err = tx.Exec(s.d.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), m.version, time.Now().Unix())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlKV) Get(key []byte) ([]byte, error) {
+	return (&sqlBucket{q: s.db, d: s.d}).Get(key)
+}
+
+func (s *sqlKV) Put(key []byte, value []byte) error {
+	return s.Update(func(txn Bucket) error {
+		return txn.Put(key, value)
+	})
+}
+
+func (s *sqlKV) Delete(key []byte) error {
+	return s.Update(func(txn Bucket) error {
+		return txn.Delete(key)
+	})
+}
+
+func (s *sqlKV) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return (&sqlBucket{q: s.db, d: s.d}).Iterate(prefix, fn)
+}
+
+// Update dùng transaction SQL, bảng projection được cập nhật cùng transaction với bảng kv
+func (s *sqlKV) Update(fn func(txn Bucket) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&sqlBucket{q: tx, d: s.d}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// View đọc trong một transaction chỉ đọc ở mức REPEATABLE READ (SQLite luôn là SERIALIZABLE)
+func (s *sqlKV) View(fn func(view Reader) error) error {
+	opts := &sql.TxOptions{ReadOnly: true}
+	if s.d.name != DriverSQLite {
+		opts.Isolation = sql.LevelRepeatableRead
+	}
+	tx, err := s.db.BeginTx(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(&sqlBucket{q: tx, d: s.d})
+}
+
+func (s *sqlKV) Close() error {
+	return s.db.Close()
+}
+
+// sqlQuerier là phần chung của *sql.DB và *sql.Tx
+type sqlQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+type sqlBucket struct {
+	q sqlQuerier
+	d *sqlDialect
+}
+
+func (b *sqlBucket) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := b.q.QueryRow(b.d.rebind(`SELECT v FROM kv WHERE k = ?`), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (b *sqlBucket) Put(key []byte, value []byte) error {
+	if value == nil {
+		value = []byte{}
+	}
+	if _, err := b.q.Exec(b.d.upsert("kv", "k", []string{"v"}), key, value); err != nil {
+		return err
+	}
+	p := projectionFor(key)
+	if p == nil {
+		return nil
+	}
+	values, err := p.values(value)
+	if err != nil {
+		return fmt.Errorf("project %s: %w", p.table, err)
+	}
+	_, err = b.q.Exec(b.d.upsert(p.table, p.key, p.columns), values...)
+	return err
+}
+
+func (b *sqlBucket) Delete(key []byte) error {
+	if _, err := b.q.Exec(b.d.rebind(`DELETE FROM kv WHERE k = ?`), key); err != nil {
+		return err
+	}
+	p := projectionFor(key)
+	if p == nil {
+		return nil
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", p.table, p.key)
+	_, err := b.q.Exec(b.d.rebind(query), string(key[len(p.prefix):]))
+	return err
+}
+
+// Iterate đọc hết các dòng trước khi gọi fn để fn được phép truy vấn tiếp trên cùng connection
+func (b *sqlBucket) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	query := `SELECT k, v FROM kv WHERE k >= ?`
+	args := []interface{}{prefix}
+	if prefix == nil {
+		args[0] = []byte{}
+	}
+	if end := prefixEnd(prefix); end != nil {
+		query += ` AND k < ?`
+		args = append(args, end)
+	}
+	rows, err := b.q.Query(b.d.rebind(query+` ORDER BY k`), args...)
+	if err != nil {
+		return err
+	}
+	var keys, values [][]byte
+	for rows.Next() {
+		var key, value []byte
+		if err := rows.Scan(&key, &value); err != nil {
+			rows.Close()
+			return err
+		}
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for i := range keys {
+		if err := fn(keys[i], values[i]); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// prefixEnd là key nhỏ nhất lớn hơn mọi key có prefix, nil nếu không có giới hạn trên
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrNotFound là lỗi của KV khi key chưa có; repository đổi sang lỗi riêng như ErrChargeNotFound
+	ErrNotFound = errors.New("key not found")
+	// ErrStopIteration được fn của Iterate trả về để dừng duyệt sớm mà không báo lỗi
+	ErrStopIteration = errors.New("stop iteration")
+)
+
+// Reader là phần đọc của Bucket. Slice key/value truyền cho fn của Iterate chỉ hợp lệ
+// trong lời gọi fn.
+type Reader interface {
+	Get(key []byte) ([]byte, error)
+	// Iterate duyệt các key có prefix theo thứ tự byte tăng dần
+	Iterate(prefix []byte, fn func(key []byte, value []byte) error) error
+}
+
+// Bucket là các thao tác key-value mà repository dùng, có trên cả KV và transaction của KV.
+type Bucket interface {
+	Reader
+	Put(key []byte, value []byte) error
+	Delete(key []byte) error
+}
+
+// KV là backend lưu trữ (LevelDB, bộ nhớ). Update chạy fn trên một transaction: mọi ghi qua
+// txn được áp dụng nguyên tử khi fn trả nil và bị bỏ khi fn lỗi; Get trong txn thấy ghi của
+// chính nó còn Iterate chỉ thấy dữ liệu đã commit.
+type KV interface {
+	Bucket
+	Update(fn func(txn Bucket) error) error
+	// View chạy fn trên một bản chụp nhất quán, ghi đồng thời không làm thay đổi dữ liệu fn đọc
+	View(fn func(view Reader) error) error
+	Close() error
+}
+
+// Store là kho dữ liệu của service, truy cập qua các repository có kiểu
+type Store interface {
+	Tokens() *TokenRepo
+	Cursors() *CursorRepo
+	Charges() *ChargeRepo
+	Events() *EventRepo
+	Merchants() *MerchantRepo
+	Settlements() *SettlementRepo
+	Challenges() *ChallengeRepo
+	Declines() *DeclineRepo
+	Maintenance() *MaintenanceRepo
+	// Update chạy fn với Store mà mọi ghi qua repository của nó nằm trong một transaction
+	Update(fn func(tx Store) error) error
+	// KV trả về backend bên dưới, dùng cho backup và công cụ
+	KV() KV
+	Close() error
+}
+
+type store struct {
+	kv KV
+	// txn khác nil khi store được tạo bởi Update
+	txn Bucket
+}
+
+// NewStore tạo Store trên một backend KV
+func NewStore(kv KV) Store {
+	return &store{kv: kv}
+}
+
+func (s *store) bucket() Bucket {
+	if s.txn != nil {
+		return s.txn
+	}
+	return s.kv
+}
+
+// update chạy fn nguyên tử; trong Update của Store thì dùng luôn transaction đang mở
+func (s *store) update(fn func(b Bucket) error) error {
+	if s.txn != nil {
+		return fn(s.txn)
+	}
+	return s.kv.Update(fn)
+}
+
+func (s *store) Tokens() *TokenRepo            { return &TokenRepo{s} }
+func (s *store) Cursors() *CursorRepo          { return &CursorRepo{s} }
+func (s *store) Charges() *ChargeRepo          { return &ChargeRepo{s} }
+func (s *store) Events() *EventRepo            { return &EventRepo{s} }
+func (s *store) Merchants() *MerchantRepo      { return &MerchantRepo{s} }
+func (s *store) Settlements() *SettlementRepo  { return &SettlementRepo{s} }
+func (s *store) Challenges() *ChallengeRepo    { return &ChallengeRepo{s} }
+func (s *store) Declines() *DeclineRepo        { return &DeclineRepo{s} }
+func (s *store) Maintenance() *MaintenanceRepo { return &MaintenanceRepo{s} }
+
+func (s *store) Update(fn func(tx Store) error) error {
+	return s.update(func(b Bucket) error {
+		return fn(&store{kv: s.kv, txn: b})
+	})
+}
+
+func (s *store) KV() KV {
+	return s.kv
+}
+
+func (s *store) Close() error {
+	return s.kv.Close()
+}
+
+// Namespace trả về Bucket mà mọi key được thêm prefix; Iterate trả key đã bỏ prefix
+func Namespace(b Bucket, prefix string) Bucket {
+	return &namespace{b: b, prefix: []byte(prefix)}
+}
+
+type namespace struct {
+	b      Bucket
+	prefix []byte
+}
+
+func (n *namespace) key(key []byte) []byte {
+	out := make([]byte, 0, len(n.prefix)+len(key))
+	return append(append(out, n.prefix...), key...)
+}
+
+func (n *namespace) Get(key []byte) ([]byte, error) {
+	return n.b.Get(n.key(key))
+}
+
+func (n *namespace) Put(key []byte, value []byte) error {
+	return n.b.Put(n.key(key), value)
+}
+
+func (n *namespace) Delete(key []byte) error {
+	return n.b.Delete(n.key(key))
+}
+
+func (n *namespace) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return n.b.Iterate(n.key(prefix), func(key []byte, value []byte) error {
+		return fn(key[len(n.prefix):], value)
+	})
+}
+
+// getJSON đọc và giải mã value, trả notFound nếu key chưa có
+func getJSON(b Bucket, key string, out interface{}, notFound error) error {
+	data, err := b.Get([]byte(key))
+	if errors.Is(err, ErrNotFound) {
+		return notFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func putJSON(b Bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+// writeTxn gom các ghi của một transaction để backend áp dụng một lần; Get đọc ghi chưa commit
+// trước rồi mới xuống base
+type writeTxn struct {
+	base    Bucket
+	ops     []writeOp
+	pending map[string]int
+}
+
+type writeOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+func newWriteTxn(base Bucket) *writeTxn {
+	return &writeTxn{base: base, pending: make(map[string]int)}
+}
+
+func (t *writeTxn) Get(key []byte) ([]byte, error) {
+	if i, ok := t.pending[string(key)]; ok {
+		op := t.ops[i]
+		if op.delete {
+			return nil, ErrNotFound
+		}
+		return append([]byte{}, op.value...), nil
+	}
+	return t.base.Get(key)
+}
+
+func (t *writeTxn) Put(key []byte, value []byte) error {
+	t.add(writeOp{key: append([]byte{}, key...), value: append([]byte{}, value...)})
+	return nil
+}
+
+func (t *writeTxn) Delete(key []byte) error {
+	t.add(writeOp{key: append([]byte{}, key...), delete: true})
+	return nil
+}
+
+func (t *writeTxn) add(op writeOp) {
+	t.ops = append(t.ops, op)
+	t.pending[string(op.key)] = len(t.ops) - 1
+}
+
+func (t *writeTxn) Iterate(prefix []byte, fn func(key []byte, value []byte) error) error {
+	return t.base.Iterate(prefix, fn)
+}
+
+// iterateJSON giải mã từng value của b thành T theo thứ tự key
+func iterateJSON[T any](b Bucket, fn func(value T) error) error {
+	return b.Iterate(nil, func(_ []byte, data []byte) error {
+		var value T
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		return fn(value)
+	})
+}
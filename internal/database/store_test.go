@@ -0,0 +1,305 @@
+package database
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+// eachBackend chạy test trên mọi backend KV nhúng, mỗi backend một Store mới
+func eachBackend(t *testing.T, test func(t *testing.T, db Store)) {
+	backends := map[string]func(t *testing.T) KV{
+		"memory": func(t *testing.T) KV { return NewMemoryKV() },
+		"leveldb": func(t *testing.T) KV {
+			kv, err := OpenLevelDB(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return kv
+		},
+	}
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			db := NewStore(open(t))
+			t.Cleanup(func() { db.Close() })
+			test(t, db)
+		})
+	}
+}
+
+func TestTokenRepo(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		tokenId := [32]byte{1}
+		if _, err := db.Tokens().Card(tokenId); !errors.Is(err, ErrTokenNotFound) {
+			t.Fatalf("Card err %v, want ErrTokenNotFound", err)
+		}
+		if err := db.Tokens().SaveCard(tokenId, []byte("encrypted")); err != nil {
+			t.Fatal(err)
+		}
+		card, err := db.Tokens().Card(tokenId)
+		if err != nil || string(card) != "encrypted" {
+			t.Fatalf("Card %q, err %v", card, err)
+		}
+
+		info, err := db.Tokens().Info(tokenId)
+		if err != nil || info.User != "" {
+			t.Fatalf("Info of token without metadata %+v, err %v", info, err)
+		}
+		user := "0xAbC0000000000000000000000000000000000001"
+		err = db.Tokens().SaveInfo(model.TokenInfo{TokenID: info.TokenID, User: user, Region: "VN"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos, err := db.Tokens().ListInfosByUser("0xabc0000000000000000000000000000000000001")
+		if err != nil || len(infos) != 1 || infos[0].Region != "VN" {
+			t.Fatalf("ListInfosByUser %+v, err %v", infos, err)
+		}
+	})
+}
+
+func TestCursorRepo(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		if _, ok, err := db.Cursors().Get("card2"); ok || err != nil {
+			t.Fatalf("Get missing cursor ok=%v err=%v", ok, err)
+		}
+		if err := db.Cursors().Set("card2", 42); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Cursors().Set("", 7); err != nil {
+			t.Fatal(err)
+		}
+		block, ok, err := db.Cursors().Get("card2")
+		if err != nil || !ok || block != 42 {
+			t.Fatalf("Get card2 = %d %v %v", block, ok, err)
+		}
+		block, ok, err = db.Cursors().Get("")
+		if err != nil || !ok || block != 7 {
+			t.Fatalf("Get default = %d %v %v", block, ok, err)
+		}
+	})
+}
+
+func TestChargeRepoIndexes(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		if _, err := db.Charges().Get("tx1"); !errors.Is(err, ErrChargeNotFound) {
+			t.Fatalf("Get err %v, want ErrChargeNotFound", err)
+		}
+		charges := []model.Charge{
+			{TxID: "tx1", TokenID: "aa", Merchant: "0xM1", Status: model.ChargePending},
+			{TxID: "tx2", TokenID: "aa", Merchant: "0xM2", Status: model.ChargePending},
+			{TxID: "tx3", TokenID: "bb", Merchant: "0xm1", Status: model.ChargeFailed},
+		}
+		for _, charge := range charges {
+			if err := db.Charges().Save(charge); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// Đổi trạng thái phải xoá index status cũ
+		charges[0].Status = model.ChargeSuccess
+		if err := db.Charges().Save(charges[0]); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tc := range []struct {
+			index, value string
+			want         int
+		}{
+			{ChargeByToken, "aa", 2},
+			{ChargeByMerchant, "0xM1", 2},
+			{ChargeByStatus, model.ChargePending, 1},
+			{ChargeByStatus, model.ChargeSuccess, 1},
+			{ChargeByStatus, model.ChargeFailed, 1},
+		} {
+			got, err := db.Charges().ListBy(tc.index, tc.value, 0)
+			if err != nil || len(got) != tc.want {
+				t.Errorf("ListBy(%s, %s) = %d charges, err %v, want %d", tc.index, tc.value, len(got), err, tc.want)
+			}
+		}
+		limited, err := db.Charges().ListBy(ChargeByToken, "aa", 1)
+		if err != nil || len(limited) != 1 {
+			t.Fatalf("ListBy limit 1 = %d, err %v", len(limited), err)
+		}
+		all, err := db.Charges().List()
+		if err != nil || len(all) != 3 {
+			t.Fatalf("List = %d, err %v", len(all), err)
+		}
+	})
+}
+
+func TestChargeRepoHeld(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		if err := db.Charges().SaveHeld(model.HeldCharge{TxID: "tx1", Amount: "100"}); err != nil {
+			t.Fatal(err)
+		}
+		held, err := db.Charges().GetHeld("tx1")
+		if err != nil || held.Amount != "100" {
+			t.Fatalf("GetHeld %+v, err %v", held, err)
+		}
+		if list, err := db.Charges().ListHeld(); err != nil || len(list) != 1 {
+			t.Fatalf("ListHeld %+v, err %v", list, err)
+		}
+		if err := db.Charges().DeleteHeld("tx1"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Charges().GetHeld("tx1"); !errors.Is(err, ErrHeldChargeNotFound) {
+			t.Fatalf("GetHeld after delete err %v", err)
+		}
+	})
+}
+
+func TestEventRepo(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		event := func(block int, index int) model.EventLog {
+			return model.EventLog{
+				BlockNumber:     "0x" + strconv.FormatInt(int64(block), 16),
+				LogIndex:        "0x" + strconv.FormatInt(int64(index), 16),
+				TransactionHash: "0xABC",
+			}
+		}
+		if seen, err := db.Events().Seen(event(10, 0)); seen || err != nil {
+			t.Fatalf("Seen before MarkSeen = %v, err %v", seen, err)
+		}
+		for _, log := range []model.EventLog{event(10, 0), event(10, 1), event(20, 0)} {
+			if err := db.Events().MarkSeen(log); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if seen, err := db.Events().Seen(event(10, 1)); !seen || err != nil {
+			t.Fatalf("Seen after MarkSeen = %v, err %v", seen, err)
+		}
+		if _, err := db.Events().Seen(model.EventLog{BlockNumber: "x"}); err == nil {
+			t.Fatal("Seen accepted an invalid blockNumber")
+		}
+
+		removed, err := db.Events().Prune(20)
+		if err != nil || removed != 2 {
+			t.Fatalf("Prune = %d, err %v, want 2", removed, err)
+		}
+		if seen, _ := db.Events().Seen(event(10, 0)); seen {
+			t.Fatal("event below the prune block is still recorded")
+		}
+		if seen, _ := db.Events().Seen(event(20, 0)); !seen {
+			t.Fatal("event at the prune block was removed")
+		}
+	})
+}
+
+func TestMerchantRepo(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		address := common.HexToAddress("0x2000000000000000000000000000000000000002")
+		if _, err := db.Merchants().Get(address); !errors.Is(err, ErrMerchantNotFound) {
+			t.Fatalf("Get err %v, want ErrMerchantNotFound", err)
+		}
+		if err := db.Merchants().Save(model.Merchant{Address: address.Hex()}); err == nil {
+			t.Fatal("Save accepted a merchant without mId")
+		}
+		if err := db.Merchants().Save(model.Merchant{Address: "0x2000000000000000000000000000000000000002", MID: "mid-1", Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		m, err := db.Merchants().Get(address)
+		if err != nil || m.MID != "mid-1" || m.Address != address.Hex() {
+			t.Fatalf("Get %+v, err %v", m, err)
+		}
+		if err := db.Merchants().Delete(address); err != nil {
+			t.Fatal(err)
+		}
+		if list, err := db.Merchants().List(); err != nil || len(list) != 0 {
+			t.Fatalf("List after delete %+v, err %v", list, err)
+		}
+	})
+}
+
+func TestSettlementRepoListPending(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		for _, s := range []model.Settlement{
+			{TxID: "tx1", Step: model.SettlementStatusUpdated},
+			{TxID: "tx2", Step: model.SettlementPoolVerified},
+			{TxID: "tx3", Step: model.SettlementUTXOMinted, NeedsReview: true},
+		} {
+			if err := db.Settlements().Save(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pending, err := db.Settlements().ListPending()
+		if err != nil || len(pending) != 1 || pending[0].TxID != "tx1" {
+			t.Fatalf("ListPending %+v, err %v", pending, err)
+		}
+	})
+}
+
+func TestStoreUpdateRollsBack(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		boom := errors.New("boom")
+		err := db.Update(func(tx Store) error {
+			if err := tx.Cursors().Set("card", 1); err != nil {
+				return err
+			}
+			if err := tx.Charges().Save(model.Charge{TxID: "tx1", Status: model.ChargePending}); err != nil {
+				return err
+			}
+			return boom
+		})
+		if !errors.Is(err, boom) {
+			t.Fatalf("Update err %v, want boom", err)
+		}
+		if _, ok, _ := db.Cursors().Get("card"); ok {
+			t.Fatal("cursor written by a failed Update")
+		}
+		if _, err := db.Charges().Get("tx1"); !errors.Is(err, ErrChargeNotFound) {
+			t.Fatalf("charge written by a failed Update: %v", err)
+		}
+
+		err = db.Update(func(tx Store) error {
+			if err := tx.Cursors().Set("card", 2); err != nil {
+				return err
+			}
+			// Get trong transaction thấy ghi của chính nó
+			block, ok, err := tx.Cursors().Get("card")
+			if err != nil || !ok || block != 2 {
+				t.Errorf("Get inside Update = %d %v %v", block, ok, err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block, ok, _ := db.Cursors().Get("card"); !ok || block != 2 {
+			t.Fatalf("cursor after commit = %d %v", block, ok)
+		}
+	})
+}
+
+// Các Update đọc-sửa-ghi cùng một key song song không được mất lần ghi nào
+func TestStoreUpdateSerializesReadModifyWrite(t *testing.T) {
+	eachBackend(t, func(t *testing.T, db Store) {
+		const writers = 50
+		var wg sync.WaitGroup
+		for i := 0; i < writers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.Update(func(tx Store) error {
+					block, _, err := tx.Cursors().Get("counter")
+					if err != nil {
+						return err
+					}
+					// Nới khoảng giữa đọc và ghi để Update khác có cơ hội chen vào
+					time.Sleep(time.Millisecond)
+					return tx.Cursors().Set("counter", block+1)
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		if block, _, _ := db.Cursors().Get("counter"); block != writers {
+			t.Fatalf("counter = %d, want %d", block, writers)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const (
+	tokenCardPrefix = "token_"
+	tokenInfoPrefix = "tokeninfo_"
+)
+
+var ErrTokenNotFound = errors.New("token not found")
+
+// TokenRepo lưu dữ liệu thẻ đã mã hoá của token và metadata TokenInfo
+type TokenRepo struct {
+	s *store
+}
+
+// SaveCard ghi encryptedCardData (pubkey client || iv || ciphertext) của token
+func (r *TokenRepo) SaveCard(tokenId [32]byte, encryptedCardData []byte) error {
+	return Namespace(r.s.bucket(), tokenCardPrefix).Put([]byte(hex.EncodeToString(tokenId[:])), encryptedCardData)
+}
+
+func (r *TokenRepo) Card(tokenId [32]byte) ([]byte, error) {
+	data, err := Namespace(r.s.bucket(), tokenCardPrefix).Get([]byte(hex.EncodeToString(tokenId[:])))
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrTokenNotFound
+	}
+	return data, err
+}
+
+func (r *TokenRepo) SaveInfo(info model.TokenInfo) error {
+	return putJSON(Namespace(r.s.bucket(), tokenInfoPrefix), info.TokenID, info)
+}
+
+// Info trả về TokenInfo rỗng nếu token được tạo trước khi có metadata.
+func (r *TokenRepo) Info(tokenId [32]byte) (model.TokenInfo, error) {
+	info := model.TokenInfo{TokenID: hex.EncodeToString(tokenId[:])}
+	err := getJSON(Namespace(r.s.bucket(), tokenInfoPrefix), info.TokenID, &info, ErrTokenNotFound)
+	if errors.Is(err, ErrTokenNotFound) {
+		return info, nil
+	}
+	return info, err
+}
+
+// ListInfosByUser duyệt toàn bộ metadata token và lọc theo địa chỉ user
+func (r *TokenRepo) ListInfosByUser(user string) ([]model.TokenInfo, error) {
+	infos := []model.TokenInfo{}
+	err := iterateJSON(Namespace(r.s.bucket(), tokenInfoPrefix), func(info model.TokenInfo) error {
+		if strings.EqualFold(info.User, user) {
+			infos = append(infos, info)
+		}
+		return nil
+	})
+	return infos, err
+}
@@ -0,0 +1,179 @@
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	pb "github.com/meta-node-blockchain/meta-node/pkg/proto"
+)
+
+const (
+	blockInterval = 200 * time.Millisecond
+	deployTimeout = 30 * time.Second
+	setupGas      = 10_000_000
+	setupGasPrice = 100_000_000_000
+)
+
+// simChain là chain giả lập trong process, mở thêm JSON-RPC HTTP để listener của
+// CardHandler đọc log như với node thật
+type simChain struct {
+	backend *simulated.Backend
+	rpcURL  string
+	chainID *big.Int
+	stop    chan struct{}
+}
+
+// newSimChain cấp ETH cho các account và tự đóng block mỗi blockInterval
+func newSimChain(accounts ...common.Address) (*simChain, error) {
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	alloc := types.GenesisAlloc{}
+	balance := new(big.Int).Mul(big.NewInt(1_000), big.NewInt(1e18))
+	for _, account := range accounts {
+		alloc[account] = types.Account{Balance: balance}
+	}
+	backend := simulated.NewBackend(alloc, func(nodeConf *node.Config, ethConf *ethconfig.Config) {
+		nodeConf.HTTPHost = "127.0.0.1"
+		nodeConf.HTTPPort = port
+		nodeConf.HTTPModules = []string{"eth", "net", "web3"}
+	})
+	chain := &simChain{
+		backend: backend,
+		rpcURL:  fmt.Sprintf("http://127.0.0.1:%d", port),
+		stop:    make(chan struct{}),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deployTimeout)
+	defer cancel()
+	chain.chainID, err = backend.Client().ChainID(ctx)
+	if err != nil {
+		backend.Close()
+		return nil, err
+	}
+	go chain.mine()
+	return chain, nil
+}
+
+func (c *simChain) mine() {
+	ticker := time.NewTicker(blockInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.backend.Commit()
+		}
+	}
+}
+
+func (c *simChain) Close() {
+	close(c.stop)
+	c.backend.Close()
+}
+
+// deploy triển khai contract từ artifact và chờ tới khi có code
+func (c *simChain) deploy(key *ecdsa.PrivateKey, art artifact, params ...interface{}) (common.Address, error) {
+	opts, err := bind.NewKeyedTransactorWithChainID(key, c.chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	opts.GasLimit = art.gasLimit()
+	opts.GasPrice = big.NewInt(setupGasPrice)
+	address, tx, _, err := bind.DeployContract(opts, art.ABI, art.Bytecode, c.backend.Client(), params...)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("deploy %s: %w", art.Name, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deployTimeout)
+	defer cancel()
+	if _, err := bind.WaitDeployed(ctx, c.backend.Client(), tx); err != nil {
+		return common.Address{}, fmt.Errorf("deploy %s: %w", art.Name, err)
+	}
+	return address, nil
+}
+
+// transact gửi một lời gọi bằng backend của account và trả lỗi kèm lý do nếu revert
+func transact(backend services.ChainBackend, to common.Address, contractAbi abi.ABI, method string, args ...interface{}) (common.Hash, error) {
+	input, err := contractAbi.Pack(method, args...)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("pack %s: %w", method, err)
+	}
+	receipt, err := backend.SendTransaction(services.TxRequest{
+		Method:      method,
+		To:          to,
+		Data:        input,
+		MaxGas:      setupGas,
+		MaxGasPrice: setupGasPrice,
+	})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("%s: %w", method, err)
+	}
+	if receipt.Status() != pb.RECEIPT_STATUS_RETURNED {
+		return receipt.TransactionHash(), fmt.Errorf("%s reverted: %s", method, services.DecodeRevert(&contractAbi, receipt.Return()))
+	}
+	return receipt.TransactionHash(), nil
+}
+
+// artifact là output của forge build (out/<file>.sol/<Contract>.json)
+type artifact struct {
+	Name     string
+	ABI      abi.ABI
+	RawABI   json.RawMessage
+	Bytecode []byte
+}
+
+func (a artifact) gasLimit() uint64 {
+	return uint64(len(a.Bytecode))*250 + 1_000_000
+}
+
+func loadArtifact(dir string, file string, name string) (artifact, error) {
+	path := filepath.Join(dir, file, name+".json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return artifact{}, fmt.Errorf("read artifact %s (chạy forge build trong contract/): %w", path, err)
+	}
+	var raw struct {
+		ABI      json.RawMessage `json:"abi"`
+		Bytecode struct {
+			Object string `json:"object"`
+		} `json:"bytecode"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return artifact{}, fmt.Errorf("parse artifact %s: %w", path, err)
+	}
+	parsed, err := abi.JSON(bytes.NewReader(raw.ABI))
+	if err != nil {
+		return artifact{}, fmt.Errorf("parse abi %s: %w", path, err)
+	}
+	bytecode := common.FromHex(raw.Bytecode.Object)
+	if len(bytecode) == 0 {
+		return artifact{}, fmt.Errorf("artifact %s has no bytecode", path)
+	}
+	return artifact{Name: name, ABI: parsed, RawABI: raw.ABI, Bytecode: bytecode}, nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,470 @@
+// Package e2e chạy pipeline thật của CardHandler trên chain giả lập trong process:
+// deploy card contract đã compile bằng forge, nối CardHandler với acquirer giả rồi đi hết
+// luồng requestToken → submitToken → charge → UpdateTxStatus → MintUTXO.
+package e2e
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/network"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	"github.com/meta-node-blockchain/cardvisa/internal/services/fake"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+const pollInterval = 500 * time.Millisecond
+
+// Options cấu hình một lần chạy
+type Options struct {
+	// Thư mục out của forge build, vd contract/out
+	ArtifactsDir string
+	// Body acquirer giả trả cho charge, rỗng thì trả phản hồi thành công của fake.Gateway
+	AcquirerResponse string
+	// Thời gian chờ tối đa cho mỗi bước
+	StepTimeout time.Duration
+	Card        model.CardData
+	// Số token charge, theo đơn vị nhỏ nhất của token
+	Amount *big.Int
+}
+
+// Step là kết quả một bước của kịch bản
+type Step struct {
+	Name     string `json:"name"`
+	TxHash   string `json:"txHash,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report là kết quả cả kịch bản; Error rỗng nghĩa là mọi bước đều qua
+type Report struct {
+	CardAddress string               `json:"cardAddress"`
+	TokenID     string               `json:"tokenId,omitempty"`
+	TxID        string               `json:"txId,omitempty"`
+	Steps       []Step               `json:"steps"`
+	Acquirer    []fake.ChargeRequest `json:"acquirer"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// harness giữ toàn bộ tài nguyên của một lần chạy
+type harness struct {
+	opts     Options
+	chain    *simChain
+	acquirer *fake.Gateway
+	db       database.Store
+	dataDir  string
+	report   *Report
+
+	adminKey  *ecdsa.PrivateKey
+	userKey   *ecdsa.PrivateKey
+	serverKey *ecdsa.PrivateKey
+	admin     services.ChainBackend
+	user      services.ChainBackend
+
+	card     artifact
+	cardAddr common.Address
+	handler  *network.CardHandler
+	query    services.ContractQuery
+}
+
+// Run dựng chain giả lập, deploy contract, chạy CardHandler và kiểm tra từng bước của luồng
+// charge thành công. Report luôn được trả về để xem các bước đã qua.
+func Run(opts Options) (*Report, error) {
+	if opts.StepTimeout <= 0 {
+		opts.StepTimeout = 60 * time.Second
+	}
+	if opts.Card.CardNumber == "" {
+		opts.Card = model.CardData{CardNumber: "4111111111111111", ExpMonth: "12", ExpYear: "2030", CVV: "123"}
+	}
+	if opts.Amount == nil {
+		opts.Amount = big.NewInt(100_000)
+	}
+	h := &harness{opts: opts, report: &Report{}}
+	defer h.close()
+
+	err := h.run()
+	if h.acquirer != nil {
+		h.report.Acquirer = h.acquirer.Charges()
+	}
+	if err != nil {
+		h.report.Error = err.Error()
+	}
+	return h.report, err
+}
+
+func (h *harness) run() error {
+	if err := h.step("setup chain", h.setupChain); err != nil {
+		return err
+	}
+	if err := h.step("deploy contracts", h.deployContracts); err != nil {
+		return err
+	}
+	if err := h.step("start handler", h.startHandler); err != nil {
+		return err
+	}
+	tokenId, err := h.requestToken()
+	if err != nil {
+		return err
+	}
+	return h.charge(tokenId)
+}
+
+// step chạy fn và ghi thời gian vào report
+func (h *harness) step(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	h.record(Step{Name: name}, start)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func (h *harness) record(step Step, start time.Time) {
+	step.Duration = time.Since(start).Round(time.Millisecond).String()
+	h.report.Steps = append(h.report.Steps, step)
+	logger.Info("e2e:", step.Name, step.TxHash, step.Detail)
+}
+
+func (h *harness) setupChain() error {
+	var err error
+	for _, key := range []**ecdsa.PrivateKey{&h.adminKey, &h.userKey, &h.serverKey} {
+		if *key, err = crypto.GenerateKey(); err != nil {
+			return err
+		}
+	}
+	h.chain, err = newSimChain(crypto.PubkeyToAddress(h.adminKey.PublicKey), crypto.PubkeyToAddress(h.userKey.PublicKey))
+	if err != nil {
+		return err
+	}
+	if h.admin, err = services.NewEthBackendWithClient(h.chain.backend.Client(), h.adminKey); err != nil {
+		return err
+	}
+	if h.user, err = services.NewEthBackendWithClient(h.chain.backend.Client(), h.userKey); err != nil {
+		return err
+	}
+	h.acquirer = fake.NewGateway(fake.OutcomeSuccess)
+	h.acquirer.Respond(h.opts.AcquirerResponse)
+	h.db = database.OpenMemory()
+	h.dataDir, err = os.MkdirTemp("", "cardvisa-e2e-")
+	return err
+}
+
+// deployContracts làm giống setUp của contract/test/card.t.sol: admin vừa là owner vừa
+// là beProcessor, user vừa là chủ thẻ vừa là merchant (charge yêu cầu merchant == msg.sender)
+func (h *harness) deployContracts() error {
+	dir := h.opts.ArtifactsDir
+	var err error
+	if h.card, err = loadArtifact(dir, "card.sol", "CardTokenManager"); err != nil {
+		return err
+	}
+	ultra, err := loadArtifact(dir, "utxo.sol", "UltraUTXO")
+	if err != nil {
+		return err
+	}
+	usdt, err := loadArtifact(dir, "usdt.sol", "USDT")
+	if err != nil {
+		return err
+	}
+	master, err := loadArtifact(dir, "masterpool.sol", "MasterPool")
+	if err != nil {
+		return err
+	}
+
+	adminAddr := h.admin.Sender()
+	userAddr := h.user.Sender()
+	if h.cardAddr, err = h.chain.deploy(h.adminKey, h.card, adminAddr); err != nil {
+		return err
+	}
+	h.report.CardAddress = h.cardAddr.Hex()
+	ultraAddr, err := h.chain.deploy(h.adminKey, ultra)
+	if err != nil {
+		return err
+	}
+	usdtAddr, err := h.chain.deploy(h.adminKey, usdt)
+	if err != nil {
+		return err
+	}
+	masterAddr, err := h.chain.deploy(h.adminKey, master, usdtAddr, ultraAddr)
+	if err != nil {
+		return err
+	}
+
+	calls := []struct {
+		to   common.Address
+		art  artifact
+		name string
+		args []interface{}
+	}{
+		{ultraAddr, ultra, "setMasterPool", []interface{}{masterAddr}},
+		{ultraAddr, ultra, "setAdmin", []interface{}{h.cardAddr, true}},
+		{h.cardAddr, h.card, "setGlobalRule", []interface{}{big.NewInt(5), big.NewInt(20), big.NewInt(50), big.NewInt(100), big.NewInt(1000)}},
+		{h.cardAddr, h.card, "setMerchantRule", []interface{}{[]string{"VN"}, big.NewInt(3), big.NewInt(10), big.NewInt(30), big.NewInt(60), userAddr}},
+		{h.cardAddr, h.card, "setBackendPubKey", []interface{}{crypto.FromECDSAPub(&h.serverKey.PublicKey)}},
+		{h.cardAddr, h.card, "setUtxoUltra", []interface{}{ultraAddr}},
+		{h.cardAddr, h.card, "setToken", []interface{}{usdtAddr}},
+	}
+	for _, call := range calls {
+		if _, err := transact(h.admin, call.to, call.art.ABI, call.name, call.args...); err != nil {
+			return err
+		}
+	}
+	return h.db.Merchants().Save(model.Merchant{
+		Address: userAddr.Hex(),
+		MID:     "E2E-MID",
+		Enabled: true,
+	})
+}
+
+// startHandler dựng CardHandler như app.NewApp nhưng với ChainBackend là chain giả lập
+func (h *harness) startHandler() error {
+	abiPath := filepath.Join(h.dataDir, "card.json")
+	if err := os.WriteFile(abiPath, h.card.RawABI, 0o644); err != nil {
+		return err
+	}
+	contract := config.CardContractConfig{
+		Name:       config.DefaultContractName,
+		Address:    h.cardAddr.Hex(),
+		ABIPath:    abiPath,
+		RpcURL:     h.chain.rpcURL,
+		StartBlock: 1,
+	}
+	cfg := &config.AppConfig{
+		CardAddress:            contract.Address,
+		CardABIPath:            abiPath,
+		RpcURL:                 h.chain.rpcURL,
+		ChainBackend:           config.BackendEthereum,
+		ThirdPartyApiUrl:       h.acquirer.ChargeURL(),
+		ChargeCurrency:         "VND",
+		ChargeCurrencyExponent: 0,
+		TokenDecimals:          0,
+		Transactions: config.TransactionsConfig{
+			Default: config.TxPolicy{
+				MaxGas:      setupGas,
+				MaxGasPrice: setupGasPrice,
+				Timeout:     h.opts.StepTimeout,
+			},
+			GasMultiplier: 1,
+		},
+	}
+
+	cardAbi := h.card.ABI
+	service := services.NewSendTransactionService(
+		services.NewTxManager(h.admin),
+		&cardAbi,
+		h.cardAddr,
+		cfg.Transactions,
+		nil,
+	)
+	var err error
+	h.query, err = services.NewEthCallQuery(h.chain.rpcURL, &cardAbi, h.cardAddr, h.admin.Sender())
+	if err != nil {
+		return err
+	}
+	eventChan := make(chan model.EventLog, 1000)
+	h.handler = network.NewCardEventHandler(
+		cfg,
+		contract,
+		service,
+		h.query,
+		&cardAbi,
+		hex.EncodeToString(crypto.FromECDSA(h.serverKey)),
+		h.db,
+		h.acquirer.ChargeURL(),
+		hex.EncodeToString(crypto.FromECDSAPub(&h.serverKey.PublicKey)),
+		eventChan,
+	)
+	deployments := network.NewDeployments(h.handler)
+	h.handler.ListenEvents()
+	go func() {
+		for event := range eventChan {
+			deployments.Dispatch(event)
+		}
+	}()
+	return nil
+}
+
+// requestToken mã hoá thẻ như client (ECDH với backendPubKey + AES-CBC) và chờ submitToken
+func (h *harness) requestToken() ([32]byte, error) {
+	var tokenId [32]byte
+	start := time.Now()
+	encrypted, err := h.encryptCard()
+	if err != nil {
+		return tokenId, fmt.Errorf("encrypt card: %w", err)
+	}
+	var requestId [32]byte
+	if _, err := rand.Read(requestId[:]); err != nil {
+		return tokenId, err
+	}
+	txHash, err := transact(h.user, h.cardAddr, h.card.ABI, "requestToken", encrypted, requestId)
+	if err != nil {
+		return tokenId, fmt.Errorf("requestToken: %w", err)
+	}
+	h.record(Step{Name: "requestToken", TxHash: txHash.Hex()}, start)
+
+	start = time.Now()
+	err = h.waitFor("submitToken", func() (bool, error) {
+		tokens, err := h.query.GetUserTokens(h.user.Sender())
+		if err != nil || len(tokens) == 0 {
+			return false, err
+		}
+		tokenId = tokens[0]
+		return true, nil
+	})
+	if err != nil {
+		return tokenId, err
+	}
+	state, err := h.query.Token(tokenId)
+	if err != nil {
+		return tokenId, fmt.Errorf("submitToken: %w", err)
+	}
+	if !state.Active || common.HexToAddress(state.Owner) != h.user.Sender() {
+		return tokenId, fmt.Errorf("submitToken: unexpected token state %+v", state)
+	}
+	h.report.TokenID = hex.EncodeToString(tokenId[:])
+	h.record(Step{Name: "submitToken", Detail: "tokenId " + h.report.TokenID}, start)
+	return tokenId, nil
+}
+
+// charge gọi charge từ ví user và chờ charge đi hết settlement saga
+func (h *harness) charge(tokenId [32]byte) error {
+	start := time.Now()
+	txHash, err := transact(h.user, h.cardAddr, h.card.ABI, "charge", tokenId, h.user.Sender(), h.opts.Amount)
+	if err != nil {
+		return fmt.Errorf("charge: %w", err)
+	}
+	h.record(Step{Name: "charge", TxHash: txHash.Hex()}, start)
+
+	start = time.Now()
+	var txID string
+	err = h.waitFor("UpdateTxStatus", func() (bool, error) {
+		id, err := h.query.GetLastTxID(tokenId)
+		if err != nil || id == "" {
+			return false, err
+		}
+		tx, err := h.query.GetTx(id)
+		if err != nil {
+			return false, err
+		}
+		txID = id
+		return tx.Status == 2, nil
+	})
+	if err != nil {
+		return err
+	}
+	h.report.TxID = txID
+	h.record(Step{Name: "UpdateTxStatus", Detail: "txId " + txID + " SUCCESS"}, start)
+
+	start = time.Now()
+	err = h.waitFor("MintUTXO", func() (bool, error) {
+		pool, err := h.query.GetPoolInfo(txID)
+		if err != nil {
+			return false, err
+		}
+		if pool.OwnerPool == (common.Address{}) {
+			return false, nil
+		}
+		if pool.OwnerPool != h.user.Sender() || pool.ParentValue.Cmp(h.opts.Amount) != 0 {
+			return false, fmt.Errorf("unexpected pool %+v", pool)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	h.record(Step{Name: "MintUTXO"}, start)
+
+	start = time.Now()
+	err = h.waitFor("settlement", func() (bool, error) {
+		charge, err := h.db.Charges().Get(txID)
+		if err != nil {
+			return false, nil
+		}
+		return charge.Status == model.ChargeSuccess, nil
+	})
+	if err != nil {
+		return err
+	}
+	if n := len(h.acquirer.Charges()); n != 1 {
+		return fmt.Errorf("acquirer received %d requests, want 1", n)
+	}
+	h.record(Step{Name: "settlement", Detail: "charge " + model.ChargeSuccess}, start)
+	return nil
+}
+
+// waitFor gọi check tới khi trả về true, lỗi hoặc hết StepTimeout
+func (h *harness) waitFor(name string, check func() (bool, error)) error {
+	deadline := time.Now().Add(h.opts.StepTimeout)
+	for {
+		ok, err := check()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: timeout after %s", name, h.opts.StepTimeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// encryptCard tạo encryptedCardData theo định dạng handleTokenRequest đọc:
+// pubkey client (65 byte) || iv (16 byte) || AES-CBC(json thẻ)
+func (h *harness) encryptCard() ([]byte, error) {
+	clientKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	sharedHex, err := utils.ECDHSharedSecretHex(crypto.FromECDSA(clientKey), crypto.FromECDSAPub(&h.serverKey.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	shared, err := hex.DecodeString(sharedHex)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := json.Marshal(h.opts.Card)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipherText, err := utils.EncryptAESCBC(shared, plain, iv)
+	if err != nil {
+		return nil, err
+	}
+	out := append(crypto.FromECDSAPub(&clientKey.PublicKey), iv...)
+	return append(out, cipherText...), nil
+}
+
+func (h *harness) close() {
+	if h.acquirer != nil {
+		h.acquirer.Close()
+	}
+	if h.db != nil {
+		h.db.Close()
+	}
+	if h.chain != nil {
+		h.chain.Close()
+	}
+	if h.dataDir != "" {
+		os.RemoveAll(h.dataDir)
+	}
+}
@@ -6,3 +6,10 @@ type CardData struct {
     ExpYear    string `json:"expireYear"`
     CVV        string `json:"cvv"`
 }
+
+// MaskedCard là dữ liệu thẻ đã che, dùng để trả ra ngoài qua API.
+type MaskedCard struct {
+	CardNumber string `json:"cardNumber"`
+	ExpMonth   string `json:"expireMonth"`
+	ExpYear    string `json:"expireYear"`
+}
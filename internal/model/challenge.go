@@ -0,0 +1,26 @@
+package model
+
+const (
+	ChallengePending = "pending"
+	ChallengePassed  = "passed"
+	ChallengeFailed  = "failed"
+
+	ChallengeTypeRedirect = "redirect"
+	ChallengeTypeOTP      = "otp"
+)
+
+// Challenge là bước xác thực chủ thẻ (3-D Secure / OTP) mà acquirer yêu cầu;
+// trong lúc chờ, giao dịch on-chain ở trạng thái being processed.
+type Challenge struct {
+	TxID        string `json:"txId"`
+	TokenID     string `json:"tokenId"`
+	CardHash    string `json:"cardHash"`
+	Merchant    string `json:"merchant"`
+	MID         string `json:"mId"`
+	Amount      string `json:"amount"`
+	Type        string `json:"type"`
+	RedirectURL string `json:"redirectUrl,omitempty"`
+	Status      string `json:"status"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
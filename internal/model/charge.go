@@ -0,0 +1,46 @@
+package model
+
+const (
+	ChargeReceived  = "received"
+	ChargeHeld      = "held"
+	ChargeChallenge = "challenge"
+	ChargePending   = "pending"
+	ChargeSettling  = "settling"
+	ChargeSuccess   = "success"
+	ChargeFailed    = "failed"
+	// acquirer trả status không nhận diện được, chờ người kiểm tra
+	ChargeReview = "review"
+)
+
+// Charge là bản ghi ledger của một ChargeRequest, lưu theo txID gửi sang acquirer
+// để mọi luồng hoàn tất (monitor, webhook, RequestUpdateTxStatus) dùng chung.
+type Charge struct {
+	TxID             string        `json:"txId"`
+	Contract         string        `json:"contract,omitempty"`
+	TokenID          string        `json:"tokenId"`
+	User             string        `json:"user"`
+	CardHash         string        `json:"cardHash"`
+	Merchant         string        `json:"merchant"`
+	MID              string        `json:"mId"`
+	Amount           string        `json:"amount"`
+	Money            Money         `json:"money"`
+	Status           string        `json:"status"`
+	Reason           string        `json:"reason"`
+	AcquirerResponse string        `json:"acquirerResponse,omitempty"`
+	RequestTxHash    string        `json:"requestTxHash"`
+	History          []ChargeEvent `json:"history"`
+	CreatedAt        int64         `json:"createdAt"`
+	UpdatedAt        int64         `json:"updatedAt"`
+}
+
+// ChargeEvent là một lần đổi trạng thái của charge.
+type ChargeEvent struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	At     int64  `json:"at"`
+}
+
+// Final cho biết charge đã kết thúc hoặc đang được settle bởi một luồng khác.
+func (c Charge) Final() bool {
+	return c.Status == ChargeSettling || c.Status == ChargeSuccess || c.Status == ChargeFailed
+}
@@ -0,0 +1,23 @@
+package model
+
+// DeclineStats đếm số lần acquirer từ chối liên tiếp của một thẻ hoặc token.
+type DeclineStats struct {
+	Consecutive int    `json:"consecutive"`
+	Total       int    `json:"total"`
+	LastReason  string `json:"lastReason"`
+	LastAt      int64  `json:"lastAt"`
+	// Locked được bật khi đã gửi giao dịch khoá thẻ/tắt token cho key này, về false khi có giao dịch thành công
+	Locked bool `json:"locked"`
+}
+
+// LockAudit ghi lại mỗi lần service tự động khoá thẻ hoặc tắt token.
+type LockAudit struct {
+	Action   string `json:"action"`
+	CardHash string `json:"cardHash,omitempty"`
+	TokenID  string `json:"tokenId,omitempty"`
+	TxID     string `json:"txId"`
+	Reason   string `json:"reason"`
+	Declines int    `json:"declines"`
+	At       int64  `json:"at"`
+	Error    string `json:"error,omitempty"`
+}
@@ -0,0 +1,11 @@
+package model
+
+// CleanUsageState lưu tiến độ job cleanUsage để chạy tiếp sau khi restart.
+type CleanUsageState struct {
+	// LastCutoff là beforeTimestamp (unix) lớn nhất đã clean thành công
+	LastCutoff    int64  `json:"lastCutoff"`
+	LastRunAt     int64  `json:"lastRunAt"`
+	LastSuccessAt int64  `json:"lastSuccessAt"`
+	Batches       int    `json:"batches"`
+	LastError     string `json:"lastError,omitempty"`
+}
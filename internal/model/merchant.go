@@ -0,0 +1,13 @@
+package model
+
+// Merchant ánh xạ địa chỉ merchant on-chain sang thông tin merchant bên acquirer.
+type Merchant struct {
+	Address    string `json:"address"`
+	MID        string `json:"mId"`
+	TerminalID string `json:"terminalId"`
+	FeePayer   int    `json:"feePayer"`
+	Enabled    bool   `json:"enabled"`
+	// Category là MCC của merchant, AllowedRegions rỗng nghĩa là không giới hạn
+	Category       string   `json:"category"`
+	AllowedRegions []string `json:"allowedRegions"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "fmt"
+
+// Money là số tiền tính theo đơn vị nhỏ nhất của currency (minor unit),
+// ví dụ Amount=1050, Exponent=2 tương đương 10.50.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Exponent int32  `json:"exponent"`
+}
+
+func (m Money) String() string {
+	if m.Exponent <= 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	digits := fmt.Sprintf("%0*d", m.Exponent+1, amount)
+	cut := len(digits) - int(m.Exponent)
+	return fmt.Sprintf("%s%s.%s %s", sign, digits[:cut], digits[cut:], m.Currency)
+}
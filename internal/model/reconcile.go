@@ -0,0 +1,36 @@
+package model
+
+// Các loại chênh lệch reconciliation phát hiện được.
+const (
+	MismatchMissingLocal    = "missing_local"
+	MismatchMissingMint     = "missing_mint"
+	MismatchDoubleMint      = "double_mint"
+	MismatchUnpaidMint      = "unpaid_mint"
+	MismatchStuckProcessing = "stuck_being_processed"
+	MismatchAmount          = "amount_difference"
+	MismatchStatus          = "status_difference"
+	MismatchUnknownStatus   = "unknown_status"
+)
+
+// SettlementRecord là một dòng trong file settlement của acquirer, Amount tính theo minor unit.
+type SettlementRecord struct {
+	TxID     string `json:"tx_id"`
+	Status   string `json:"status"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+type Mismatch struct {
+	TxID        string `json:"txId"`
+	Kind        string `json:"kind"`
+	Detail      string `json:"detail"`
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repairError,omitempty"`
+}
+
+type ReconcileReport struct {
+	Source      string     `json:"source"`
+	GeneratedAt int64      `json:"generatedAt"`
+	Checked     int        `json:"checked"`
+	Mismatches  []Mismatch `json:"mismatches"`
+}
@@ -0,0 +1,31 @@
+package model
+
+// MerchantRule là giới hạn tần suất charge của một merchant trên contract (setMerchantRule).
+type MerchantRule struct {
+	Merchant       string   `json:"merchant"`
+	AllowedRegions []string `json:"allowedRegions"`
+	MaxPerMinute   uint64   `json:"maxPerMinute"`
+	MaxPerHour     uint64   `json:"maxPerHour"`
+	MaxPerDay      uint64   `json:"maxPerDay"`
+	MaxPerWeek     uint64   `json:"maxPerWeek"`
+}
+
+// GlobalRule là giới hạn tần suất áp dụng cho mọi token và thẻ (setGlobalRule / smRule).
+type GlobalRule struct {
+	MaxPerMinute uint64 `json:"maxPerMinute"`
+	MaxPerHour   uint64 `json:"maxPerHour"`
+	MaxPerDay    uint64 `json:"maxPerDay"`
+	MaxPerWeek   uint64 `json:"maxPerWeek"`
+	MaxTotal     uint64 `json:"maxTotal"`
+}
+
+// TokenState là bản ghi token trên contract (mapping tokens), khác TokenInfo lưu ở LevelDB.
+type TokenState struct {
+	TokenID    string `json:"tokenId"`
+	Owner      string `json:"owner"`
+	Region     string `json:"region"`
+	IssuedAt   uint64 `json:"issuedAt"`
+	Active     bool   `json:"active"`
+	TotalUsage uint64 `json:"totalUsage"`
+	CardHash   string `json:"cardHash"`
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Các bước của settlement saga, theo đúng thứ tự thực hiện.
+const (
+	SettlementGatewayConfirmed = "gateway_confirmed"
+	SettlementStatusUpdated    = "status_updated"
+	SettlementUTXOMinted       = "utxo_minted"
+	SettlementPoolVerified     = "pool_verified"
+)
+
+// Settlement lưu tiến trình hoàn tất một charge thành công (UpdateTxStatus -> MintUTXO -> getPoolInfo)
+// để có thể retry và tiếp tục sau khi service khởi động lại.
+type Settlement struct {
+	TxID     string `json:"txId"`
+	TokenID  string `json:"tokenId"`
+	Merchant string `json:"merchant"`
+	Amount   string `json:"amount"`
+	Step     string `json:"step"`
+	Attempts int    `json:"attempts"`
+	// Mints đếm số lần MintUTXO đã được gửi cho giao dịch
+	Mints int `json:"mints"`
+	// Pool là địa chỉ pool MintUTXO thành công gần nhất trả về, để đối chiếu với getPoolInfo
+	Pool string `json:"pool,omitempty"`
+	// PoolChecks đếm số lần getPoolInfo trả pool rỗng sau khi MintUTXO đã thành công
+	PoolChecks int    `json:"poolChecks,omitempty"`
+	LastError  string `json:"lastError,omitempty"`
+	// NeedsReview được bật khi pool on-chain không khớp hoặc không thấy pool sau nhiều lần đọc;
+	// saga dừng retry chờ xử lý tay
+	NeedsReview bool `json:"needsReview"`
+	// Người/tiến trình gỡ NeedsReview gần nhất, thời điểm và ghi chú
+	ReviewedBy string `json:"reviewedBy,omitempty"`
+	ReviewedAt int64  `json:"reviewedAt,omitempty"`
+	ReviewNote string `json:"reviewNote,omitempty"`
+	CreatedAt  int64  `json:"createdAt"`
+	UpdatedAt  int64  `json:"updatedAt"`
+}
+
+func (s Settlement) Done() bool {
+	return s.Step == SettlementPoolVerified
+}
+
+// ValidSettlementStep kiểm tra step có phải một bước của saga
+func ValidSettlementStep(step string) bool {
+	switch step {
+	case SettlementGatewayConfirmed, SettlementStatusUpdated, SettlementUTXOMinted, SettlementPoolVerified:
+		return true
+	}
+	return false
+}
+
+// PoolInfo là kết quả getPoolInfo của contract.
+type PoolInfo struct {
+	OwnerPool   common.Address `json:"ownerPool"`
+	ParentHash  [32]byte       `json:"parentHash"`
+	Pool        common.Address `json:"pool"`
+	ParentValue *big.Int       `json:"parentValue"`
+}
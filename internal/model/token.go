@@ -0,0 +1,31 @@
+package model
+
+// TokenInfo là metadata của token đã submit lên contract, không chứa dữ liệu thẻ.
+type TokenInfo struct {
+	TokenID  string `json:"tokenId"`
+	User     string `json:"user"`
+	Region   string `json:"region"`
+	CardHash string `json:"cardHash"`
+	IssuedAt int64  `json:"issuedAt"`
+	Contract string `json:"contract,omitempty"`
+}
+
+// HeldCharge là charge bị risk engine giữ lại chờ duyệt.
+type HeldCharge struct {
+	TxID      string `json:"txId"`
+	TokenID   string `json:"tokenId"`
+	Merchant  string `json:"merchant"`
+	Amount    string `json:"amount"`
+	Rule      string `json:"rule"`
+	Reason    string `json:"reason"`
+	CreatedAt int64  `json:"createdAt"`
+	// Decision do lệnh `cardvisa held` ghi khi service dừng, service áp dụng lúc khởi động
+	Decision  string `json:"decision,omitempty"`
+	DecidedBy string `json:"decidedBy,omitempty"`
+}
+
+// Quyết định duyệt một HeldCharge.
+const (
+	HeldApprove = "approve"
+	HeldDecline = "decline"
+)
@@ -4,10 +4,19 @@ type TxResponse struct {
     Message       string `json:"message"`
     Status        string `json:"status"`
     TransactionID string `json:"transactionID"`
+    Amount        Money  `json:"-"`
+    Challenge     *Challenge `json:"-"`
 }
 
 // Channel để nhận kết quả hoặc lỗi
 type ResultData struct {
     Receipt types.Receipt
     Err     error
-}
\ No newline at end of file
+}
+// TxStatus là kết quả getTx của contract, Status: 0 failed, 1 being processed, 2 success
+type TxStatus struct {
+    TxID   string `json:"txID"`
+    Status uint8  `json:"status"`
+    AtTime uint64 `json:"atTime"`
+    Reason string `json:"reason"`
+}
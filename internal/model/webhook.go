@@ -0,0 +1,52 @@
+package model
+
+import "strings"
+
+// AcquirerWebhook là callback acquirer gửi về khi trạng thái giao dịch thay đổi.
+type AcquirerWebhook struct {
+	TxID    string `json:"tx_id"`
+	MID     string `json:"m_id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Trạng thái chuẩn hoá của status acquirer gửi về (webhook, truy vấn trạng thái)
+const (
+	AcquirerApproved = "approved"
+	AcquirerDeclined = "declined"
+	AcquirerPending  = "pending"
+)
+
+var acquirerStatuses = map[string]string{
+	"approved":        AcquirerApproved,
+	"success":         AcquirerApproved,
+	"succeeded":       AcquirerApproved,
+	"successful":      AcquirerApproved,
+	"completed":       AcquirerApproved,
+	"settled":         AcquirerApproved,
+	"declined":        AcquirerDeclined,
+	"failed":          AcquirerDeclined,
+	"failure":         AcquirerDeclined,
+	"rejected":        AcquirerDeclined,
+	"refused":         AcquirerDeclined,
+	"pending":         AcquirerPending,
+	"processing":      AcquirerPending,
+	"being processed": AcquirerPending,
+	"in_progress":     AcquirerPending,
+}
+
+// NormalizeAcquirerStatus so khớp nguyên vẹn status (không phân biệt hoa thường), không dùng
+// chứa chuỗi con vì "unsuccessful" cũng chứa "success". Status lạ trả về "".
+func NormalizeAcquirerStatus(status string) string {
+	return acquirerStatuses[strings.ToLower(strings.TrimSpace(status))]
+}
+
+// AcquirerStatus chuẩn hoá status kèm message. Cùng quy ước với phản hồi charge:
+// failed kèm message "Transaction failed, pending" là đang xử lý.
+func AcquirerStatus(status string, message string) string {
+	normalized := NormalizeAcquirerStatus(status)
+	if normalized == AcquirerDeclined && strings.Contains(message, "Transaction failed, pending") {
+		return AcquirerPending
+	}
+	return normalized
+}
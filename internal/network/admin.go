@@ -0,0 +1,63 @@
+package network
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// Contract trả về service gửi transaction tới card contract, dùng cho các API quản trị
+func (h *CardHandler) Contract() services.SendTransactionService {
+	return h.service
+}
+
+// SetCardLocked khoá hoặc mở khoá thẻ theo yêu cầu của admin, ghi lại vào lock audit
+func (h *CardHandler) SetCardLocked(cardHash [32]byte, locked bool, reason string) error {
+	action := "unlock_card"
+	if locked {
+		action = "lock_card"
+	}
+	_, err := h.service.SetCardLocked(cardHash, locked)
+	return h.auditAdmin(model.LockAudit{
+		Action:   action,
+		CardHash: hex.EncodeToString(cardHash[:]),
+		Reason:   reason,
+		At:       time.Now().Unix(),
+	}, err)
+}
+
+// SetTokenActive bật hoặc tắt token theo yêu cầu của admin, ghi lại vào lock audit
+func (h *CardHandler) SetTokenActive(tokenId [32]byte, active bool, reason string) error {
+	action := "deactivate_token"
+	if active {
+		action = "activate_token"
+	}
+	_, err := h.service.SetTokenActive(tokenId, active)
+	return h.auditAdmin(model.LockAudit{
+		Action:  action,
+		TokenID: hex.EncodeToString(tokenId[:]),
+		Reason:  reason,
+		At:      time.Now().Unix(),
+	}, err)
+}
+
+func (h *CardHandler) auditAdmin(audit model.LockAudit, err error) error {
+	if err != nil {
+		logger.Error("fail in admin "+audit.Action+":", err)
+		audit.Error = err.Error()
+	} else {
+		logger.Warn("🔒 Admin "+audit.Action+":", audit.CardHash+audit.TokenID, audit.Reason)
+	}
+	if err := h.DB.Declines().SaveLockAudit(audit); err != nil {
+		logger.Error("fail in save lock audit:", err)
+	}
+	return err
+}
+
+// Query trả về client đọc state của card contract
+func (h *CardHandler) Query() services.ContractQuery {
+	return h.query
+}
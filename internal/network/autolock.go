@@ -0,0 +1,149 @@
+package network
+
+import (
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// recordDecline tăng bộ đếm từ chối của thẻ và token, khoá thẻ/tắt token khi chạm ngưỡng cấu hình
+func (h *CardHandler) recordDecline(tokenId [32]byte, cardHash [32]byte, txID string, reason string) {
+	cfg := h.config.AutoLock
+	if !cfg.Enabled {
+		return
+	}
+	now := time.Now().Unix()
+	cardKey := "card_" + hex.EncodeToString(cardHash[:])
+	tokenKey := "token_" + hex.EncodeToString(tokenId[:])
+
+	lockReason := matchesAny(reason, cfg.LockReasons)
+	cardStats, lockCard, err := h.bumpDecline(cardKey, reason, now, lockReason, cfg.MaxCardDeclines)
+	if err != nil {
+		logger.Error("fail in save card decline stats:", err)
+		return
+	}
+	tokenStats, deactivateToken, err := h.bumpDecline(tokenKey, reason, now, lockReason, cfg.MaxTokenDeclines)
+	if err != nil {
+		logger.Error("fail in save token decline stats:", err)
+		return
+	}
+
+	if lockCard {
+		_, err := h.service.SetCardLocked(cardHash, true)
+		if err != nil {
+			h.unmarkLocked(cardKey)
+		}
+		h.auditLock(model.LockAudit{
+			Action:   "lock_card",
+			CardHash: hex.EncodeToString(cardHash[:]),
+			TxID:     txID,
+			Reason:   reason,
+			Declines: cardStats.Consecutive,
+			At:       now,
+		}, err)
+	}
+	if deactivateToken {
+		_, err := h.service.SetTokenActive(tokenId, false)
+		if err != nil {
+			h.unmarkLocked(tokenKey)
+		}
+		h.auditLock(model.LockAudit{
+			Action:   "deactivate_token",
+			TokenID:  hex.EncodeToString(tokenId[:]),
+			TxID:     txID,
+			Reason:   reason,
+			Declines: tokenStats.Consecutive,
+			At:       now,
+		}, err)
+	}
+}
+
+// resetDeclines đưa bộ đếm liên tiếp về 0 khi giao dịch thành công
+func (h *CardHandler) resetDeclines(tokenId [32]byte, cardHash [32]byte) {
+	if !h.config.AutoLock.Enabled {
+		return
+	}
+	for _, key := range []string{"card_" + hex.EncodeToString(cardHash[:]), "token_" + hex.EncodeToString(tokenId[:])} {
+		err := h.updateDeclines(key, func(stats *model.DeclineStats) bool {
+			if stats.Consecutive == 0 && !stats.Locked {
+				return false
+			}
+			stats.Consecutive = 0
+			stats.Locked = false
+			return true
+		})
+		if err != nil {
+			logger.Error("fail in reset decline stats:", err)
+		}
+	}
+}
+
+// bumpDecline tăng bộ đếm của key và cho biết lần từ chối này có vừa vượt ngưỡng hay không.
+// Locked được bật ngay trong cùng lần cập nhật nên chỉ một lần từ chối gửi giao dịch khoá.
+func (h *CardHandler) bumpDecline(key string, reason string, at int64, lockReason bool, max int) (model.DeclineStats, bool, error) {
+	var stats model.DeclineStats
+	lock := false
+	err := h.updateDeclines(key, func(s *model.DeclineStats) bool {
+		s.Consecutive++
+		s.Total++
+		s.LastReason = reason
+		s.LastAt = at
+		if !s.Locked && (lockReason || (max > 0 && s.Consecutive >= max)) {
+			s.Locked = true
+			lock = true
+		}
+		stats = *s
+		return true
+	})
+	return stats, lock, err
+}
+
+// unmarkLocked bỏ cờ Locked khi gửi giao dịch khoá lỗi để lần từ chối sau thử lại
+func (h *CardHandler) unmarkLocked(key string) {
+	err := h.updateDeclines(key, func(stats *model.DeclineStats) bool {
+		stats.Locked = false
+		return true
+	})
+	if err != nil {
+		logger.Error("fail in save decline stats:", err)
+	}
+}
+
+// updateDeclines đọc, sửa và ghi bộ đếm của key dưới declineMu; update trả false nếu không cần ghi
+func (h *CardHandler) updateDeclines(key string, update func(stats *model.DeclineStats) bool) error {
+	h.declineMu.Lock()
+	defer h.declineMu.Unlock()
+	stats, err := h.DB.Declines().Stats(key)
+	if err != nil {
+		return err
+	}
+	if !update(&stats) {
+		return nil
+	}
+	return h.DB.Declines().SaveStats(key, stats)
+}
+
+func (h *CardHandler) auditLock(audit model.LockAudit, err error) {
+	if err != nil {
+		logger.Error("fail in auto "+audit.Action+":", err)
+		audit.Error = err.Error()
+	} else {
+		logger.Warn("🔒 Auto "+audit.Action+":", audit.CardHash+audit.TokenID, audit.Reason)
+	}
+	if err := h.DB.Declines().SaveLockAudit(audit); err != nil {
+		logger.Error("fail in save lock audit:", err)
+	}
+}
+
+func matchesAny(reason string, patterns []string) bool {
+	reason = strings.ToLower(reason)
+	for _, p := range patterns {
+		if p != "" && strings.Contains(reason, strings.ToLower(p)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package network
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	e_common "github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+func (h *CardHandler) saveChallenge(
+	challenge model.Challenge,
+	tokenId [32]byte,
+	cardHash [32]byte,
+	amount *big.Int,
+	merchant common.Address,
+	mID string,
+) {
+	now := time.Now().Unix()
+	challenge.TokenID = hex.EncodeToString(tokenId[:])
+	challenge.CardHash = hex.EncodeToString(cardHash[:])
+	challenge.Merchant = merchant.Hex()
+	challenge.MID = mID
+	challenge.Amount = amount.String()
+	challenge.Status = model.ChallengePending
+	challenge.CreatedAt = now
+	challenge.UpdatedAt = now
+	if err := h.DB.Challenges().Save(challenge); err != nil {
+		logger.Error("fail in save challenge:", err)
+	}
+}
+
+// GetChallenge trả về challenge đang chờ của giao dịch để wallet app hiển thị cho chủ thẻ
+func (h *CardHandler) GetChallenge(txID string) (model.Challenge, error) {
+	return h.DB.Challenges().Get(txID)
+}
+
+// ResolveChallenge nhận kết quả xác thực chủ thẻ. Với OTP, mã được chuyển tiếp sang acquirer;
+// với redirect, passed là kết quả acquirer trả về sau khi chủ thẻ hoàn tất trang xác thực.
+// Thành công thì tiếp tục theo dõi giao dịch như một giao dịch đang xử lý.
+func (h *CardHandler) ResolveChallenge(txID string, passed bool, otp string) (model.Challenge, error) {
+	if owner := h.forTx(txID); owner != h {
+		return owner.ResolveChallenge(txID, passed, otp)
+	}
+	challenge, err := h.DB.Challenges().Get(txID)
+	if err != nil {
+		return challenge, err
+	}
+	if challenge.Status != model.ChallengePending {
+		return challenge, fmt.Errorf("challenge already %s", challenge.Status)
+	}
+
+	if challenge.Type == model.ChallengeTypeOTP {
+		if otp == "" {
+			return challenge, fmt.Errorf("otp is required")
+		}
+		if h.config.ChallengeApiUrl == "" {
+			return challenge, fmt.Errorf("ChallengeApiUrl is not configured")
+		}
+		kq, err := utils.SubmitChallengeResult(h.config.ChallengeApiUrl, txID, challenge.MID, otp)
+		if err != nil {
+			return challenge, err
+		}
+		passed = kq.Status != "failed"
+	}
+
+	var tokenId, cardHash [32]byte
+	copy(tokenId[:], e_common.FromHex(challenge.TokenID))
+	copy(cardHash[:], e_common.FromHex(challenge.CardHash))
+	challenge.UpdatedAt = time.Now().Unix()
+	if passed {
+		challenge.Status = model.ChallengePassed
+	} else {
+		challenge.Status = model.ChallengeFailed
+	}
+	if err := h.DB.Challenges().Save(challenge); err != nil {
+		return challenge, err
+	}
+
+	if !passed {
+		logger.Info("❌ Xác thực chủ thẻ thất bại:", txID)
+		h.failCharge(tokenId, cardHash, txID, "cardholder authentication failed")
+		return challenge, nil
+	}
+	amount, ok := new(big.Int).SetString(challenge.Amount, 10)
+	if !ok {
+		return challenge, fmt.Errorf("invalid challenge amount %q", challenge.Amount)
+	}
+	logger.Info("✅ Xác thực chủ thẻ thành công, tiếp tục theo dõi giao dịch:", txID)
+	h.transitionCharge(txID, model.ChargePending, "cardholder authenticated")
+	h.startMonitor(tokenId, txID, amount, common.HexToAddress(challenge.Merchant), challenge.MID, time.Now())
+	return challenge, nil
+}
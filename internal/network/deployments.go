@@ -0,0 +1,126 @@
+package network
+
+import (
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// Deployments gom CardHandler của từng card contract. Các handler dùng chung Store và
+// event channel; mỗi handler có listener, cursor, service và ABI riêng của deployment.
+type Deployments struct {
+	handlers  []*CardHandler
+	byName    map[string]*CardHandler
+	byAddress map[string]*CardHandler
+}
+
+// NewDeployments đăng ký các handler, handler đầu tiên là deployment chính
+func NewDeployments(handlers ...*CardHandler) *Deployments {
+	d := &Deployments{
+		handlers:  handlers,
+		byName:    make(map[string]*CardHandler, len(handlers)),
+		byAddress: make(map[string]*CardHandler, len(handlers)),
+	}
+	for _, h := range handlers {
+		h.deployments = d
+		d.byName[h.contract.Name] = h
+		d.byAddress[addressKey(h.contract.Address)] = h
+	}
+	return d
+}
+
+// Primary là deployment đầu tiên, dùng cho API admin và các job chạy một lần cho toàn service
+func (d *Deployments) Primary() *CardHandler {
+	return d.handlers[0]
+}
+
+func (d *Deployments) All() []*CardHandler {
+	return d.handlers
+}
+
+func (d *Deployments) ByName(name string) (*CardHandler, bool) {
+	h, ok := d.byName[name]
+	return h, ok
+}
+
+// Dispatch chuyển event tới handler của contract phát ra event
+func (d *Deployments) Dispatch(event model.EventLog) {
+	h, ok := d.byAddress[addressKey(event.Address)]
+	if !ok {
+		logger.Warn("event from unknown contract:", event.Address)
+		return
+	}
+	h.HandleConnectSmartContract(event)
+}
+
+// addressKey chuẩn hoá địa chỉ từ config (có thể thiếu 0x) và từ eth_getLogs để so khớp
+func addressKey(address string) string {
+	return strings.ToLower(common.HexToAddress(address).Hex())
+}
+
+// ContractConfig trả về cấu hình deployment của handler
+func (h *CardHandler) ContractConfig() config.CardContractConfig {
+	return h.contract
+}
+
+// cursorName là tên cursor của deployment; deployment mặc định dùng tên rỗng để giữ key cũ
+func (h *CardHandler) cursorName() string {
+	if h.contract.Name == config.DefaultContractName {
+		return ""
+	}
+	return h.contract.Name
+}
+
+// owner trả về handler của deployment đã tạo charge; charge cũ chưa ghi Contract thuộc về
+// deployment chính
+func (h *CardHandler) owner(charge model.Charge) *CardHandler {
+	if h.deployments == nil {
+		return h
+	}
+	if owner, ok := h.deployments.ByName(charge.Contract); ok {
+		return owner
+	}
+	return h.deployments.Primary()
+}
+
+// forTx tìm handler sở hữu giao dịch txID, không tìm thấy charge thì giữ handler hiện tại
+func (h *CardHandler) forTx(txID string) *CardHandler {
+	if h.deployments == nil {
+		return h
+	}
+	charge, err := h.DB.Charges().Get(txID)
+	if err != nil {
+		return h
+	}
+	return h.owner(charge)
+}
+
+// Deployments trả về mọi deployment cùng service, chỉ có handler này nếu chưa đăng ký
+func (h *CardHandler) Deployments() []*CardHandler {
+	if h.deployments == nil {
+		return []*CardHandler{h}
+	}
+	return h.deployments.All()
+}
+
+// Deployment tìm handler theo tên deployment; tên rỗng là deployment chính
+func (h *CardHandler) Deployment(name string) (*CardHandler, bool) {
+	if name == "" {
+		if h.deployments == nil {
+			return h, true
+		}
+		return h.deployments.Primary(), true
+	}
+	if h.deployments == nil {
+		return h, h.contract.Name == name
+	}
+	return h.deployments.ByName(name)
+}
+
+// LastBlock trả về block cuối listener của deployment đã quét, ok=false nếu chưa có cursor
+func (h *CardHandler) LastBlock() (uint64, bool, error) {
+	return h.DB.Cursors().Get(h.cursorName())
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"os"
@@ -22,32 +23,47 @@ import (
 	"github.com/meta-node-blockchain/cardvisa/internal/config"
 	"github.com/meta-node-blockchain/cardvisa/internal/database"
 	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/risk"
 	"github.com/meta-node-blockchain/cardvisa/internal/services"
 	"github.com/meta-node-blockchain/cardvisa/internal/utils"
 	"github.com/meta-node-blockchain/meta-node/pkg/logger"
-	"github.com/syndtr/goleveldb/leveldb"
 
 )
 
+// eventRetentionBlocks là số block event log được giữ lại để chống xử lý trùng khi quét lại
+const eventRetentionBlocks = 100_000
+
 type CardHandler struct {
 	config           *config.AppConfig
+	contract         config.CardContractConfig
+	deployments      *Deployments
 	service          services.SendTransactionService
+	query            services.ContractQuery
 	cardABI          *abi.ABI
 	ServerPrivateKey string
-	DB               *leveldb.DB
+	DB               database.Store
 	thirdPartyURL    string
 	storedPubKey     string
 	eventChan        chan model.EventLog
 	cancelMonitors   map[string]context.CancelFunc
 	cancelMu         sync.Mutex
+	chargeMu         sync.Mutex
+	releasing        map[string]bool
+	declineMu        sync.Mutex
+	settling         map[string]bool
+	cleanMu          sync.Mutex
+	cleaning         bool
+	risk             *risk.Engine
 }
 
 func NewCardEventHandler(
 	config *config.AppConfig,
+	contract config.CardContractConfig,
 	service services.SendTransactionService,
+	query services.ContractQuery,
 	cardABI *abi.ABI,
 	ServerPrivateKey string,
-	DB *leveldb.DB,
+	DB database.Store,
 	thirdPartyURL string,
 	storedPubKey string,
 	eventChan chan model.EventLog,
@@ -57,7 +73,9 @@ func NewCardEventHandler(
     }
 	return &CardHandler{
 		config:           config,
+		contract:         contract,
 		service:          service,
+		query:            query,
 		cardABI:          cardABI,
 		ServerPrivateKey: ServerPrivateKey,
 		DB:               DB,
@@ -65,21 +83,19 @@ func NewCardEventHandler(
 		storedPubKey:     storedPubKey,
 		eventChan:        eventChan,
 		cancelMonitors:   make(map[string]context.CancelFunc),
+		releasing:        make(map[string]bool),
+		settling:         make(map[string]bool),
+		risk:             risk.FromConfig(config.Risk),
 	}
 }
 
 func (h *CardHandler) VerifyPublicKey() {
-	serverPubKey, err := h.service.CallVerifyPublicKey()
+	serverPubKeyBytes, err := h.query.GetBackendPubKey()
 	if err != nil {
 		logger.Error("Không thể lấy khóa công khai từ smart contract: %v", err)
 		return
 	}
 	storedPubKeyBytes, err := hex.DecodeString(h.storedPubKey)
-	serverPubKeyBytes, ok := serverPubKey.([]byte)
-	if !ok {
-		logger.Error("Error when parse server PubKey.")
-		return
-	}
 	if !bytes.Equal(serverPubKeyBytes, storedPubKeyBytes) {
 		logger.Error("Khóa công khai không khớp với smart contract.")
 		return
@@ -89,13 +105,13 @@ func (h *CardHandler) VerifyPublicKey() {
 }
 func (h *CardHandler) ListenEvents() {
 	go func() {
-		logger.Info("⏳ Start listening for new events...")
+		logger.Info("⏳ Start listening for new events...", h.contract.Name)
 
-		rpcURL := h.config.RpcURL
-		contractAddress := h.config.CardAddress
+		rpcURL := h.contract.RpcURL
+		contractAddress := h.contract.Address
 
 		// Đọc ABI
-		abiBytes, err := os.ReadFile(h.config.CardABIPath)
+		abiBytes, err := os.ReadFile(h.contract.ABIPath)
 		if err != nil {
 			logger.Error("Error reading ABI file:", err)
 			return
@@ -118,39 +134,42 @@ func (h *CardHandler) ListenEvents() {
 			logger.Error("Error getting ChargeRejected topic0:", err)
 			return
 		}
+		requestUpdateTxStatusTopic, err := utils.GetTopic0FromABI(abiJSON, "RequestUpdateTxStatus")
+		if err != nil {
+			logger.Error("Error getting RequestUpdateTxStatus topic0:", err)
+			return
+		}
 
 		// var lastBlock string
 		// Lấy last block từ DB (nếu có)
 		var fromBlock uint64 = 0
-		callmap := map[string]interface{}{
-			"key": "lastBlock",
-		}
+		cursorName := h.cursorName()
 		if h.DB != nil {
-			lastBlockBytes, err := database.ReadValueStorage(callmap, h.DB)
-			if err != nil || len(lastBlockBytes) == 0 {
-				// Nếu chưa có lastBlock trong DB, lấy latest block hiện tại
-				latestBlockStr, err := utils.GetLatestBlockNumber(rpcURL)
-				if err != nil {
-					logger.Error("Failed to get latest block on first load:", err)
-					return
+			lastBlock, ok, err := h.DB.Cursors().Get(cursorName)
+			if err != nil || !ok {
+				// Nếu chưa có lastBlock trong DB, bắt đầu từ StartBlock hoặc latest block hiện tại
+				if h.contract.StartBlock > 0 {
+					fromBlock = h.contract.StartBlock - 1
+				} else {
+					latestBlockStr, err := utils.GetLatestBlockNumber(rpcURL)
+					if err != nil {
+						logger.Error("Failed to get latest block on first load:", err)
+						return
+					}
+					latestBlockUint, _ := strconv.ParseUint(latestBlockStr, 0, 64)
+
+					fromBlock = latestBlockUint // Ghi nhận block hiện tại làm mốc đầu tiên
 				}
-				latestBlockUint, _ := strconv.ParseUint(latestBlockStr, 0, 64)
-		
-				fromBlock = latestBlockUint // Ghi nhận block hiện tại làm mốc đầu tiên
 		
 				// Ghi vào DB để lần sau sử dụng lại
-				callmapWrite := map[string]interface{}{
-					"key":  "lastBlock",
-					"data": strconv.FormatUint(fromBlock, 10),
-				}
-				err = database.WriteValueStorage(callmapWrite, h.DB)
+				err = h.DB.Cursors().Set(cursorName, fromBlock)
 				if err != nil {
 					logger.Error("Failed to save initial lastBlock to DB:", err)
 				}
 		
 				logger.Info("🟢 First time setup: recorded current block as lastBlock: %d", fromBlock)
 			} else {
-				fromBlock, _ = strconv.ParseUint(string(lastBlockBytes), 0, 64)
+				fromBlock = lastBlock
 			}
 			for {
 				select {
@@ -170,7 +189,7 @@ func (h *CardHandler) ListenEvents() {
 					}
 					const maxBlockRange = 10000
 					// Lặp qua từng topic để lấy log
-					topics := []string{tokenRequestTopic, chargeRequestTopic, chargeRejectedTopic}
+					topics := []string{tokenRequestTopic, chargeRequestTopic, chargeRejectedTopic, requestUpdateTxStatusTopic}
 					for _, topic := range topics {
 						currentFrom := fromBlock + 1
 						for currentFrom <= latestBlockUint {
@@ -196,20 +215,28 @@ func (h *CardHandler) ListenEvents() {
 									logger.Warn("Cannot decode event log:", err)
 									continue
 								}
+								// Quét lại sau restart có thể gặp event đã xử lý xong; event chỉ được
+								// ghi nhận sau khi HandleConnectSmartContract chạy xong
+								if seen, err := h.DB.Events().Seen(log); err != nil {
+									logger.Warn("Cannot read event log:", err)
+								} else if seen {
+									logger.Info("Skip event already handled:", log.TransactionHash, log.LogIndex)
+									continue
+								}
 								h.eventChan <- log
 							}
 							currentFrom = currentTo + 1
 						}
 					}
-					callmap := map[string]interface{}{
-						"key":  "lastBlock",
-						"data": (strconv.FormatUint(latestBlockUint, 10)),
-					}
-					err = database.WriteValueStorage(callmap, h.DB)
-			
+					err = h.DB.Cursors().Set(cursorName, latestBlockUint)
 					if err != nil {
 						logger.Error("Failed to save lastBlock to DB:", err)
 					}
+					if latestBlockUint > eventRetentionBlocks {
+						if _, err := h.DB.Events().Prune(latestBlockUint - eventRetentionBlocks); err != nil {
+							logger.Error("Failed to prune event logs:", err)
+						}
+					}
 					fromBlock = latestBlockUint
 
 					time.Sleep(1 * time.Second)
@@ -225,13 +252,19 @@ func (h *CardHandler) HandleConnectSmartContract(event model.EventLog) {
 	case h.cardABI.Events["TokenRequest"].ID.String():
 		h.handleTokenRequest(event.Data)
 	case h.cardABI.Events["ChargeRequest"].ID.String():
-		h.handleChargeRequest(event.Data)
+		h.handleChargeRequest(event.Data, event.TransactionHash)
 	case h.cardABI.Events["ChargeRejected"].ID.String():
 		h.handleChargeRejected(event.Data)
 	case h.cardABI.Events["RequestUpdateTxStatus"].ID.String():
 		h.handleRequestUpdateTxStatus(event.Data)
 	}
-
+	// Event không đến từ eth_getLogs (không có block) thì không cần ghi nhận
+	if event.BlockNumber == "" {
+		return
+	}
+	if err := h.DB.Events().MarkSeen(event); err != nil {
+		logger.Warn("Cannot record event log:", err)
+	}
 }
 func (h *CardHandler) handleRequestUpdateTxStatus(data string) {
 	fmt.Println("handleRequestUpdateTxStatus")
@@ -251,42 +284,32 @@ func (h *CardHandler) handleRequestUpdateTxStatus(data string) {
 		logger.Error("fail in parse tokenId RequestUpdateTxStatus:", err)
 		return
 	}
-	h.cancelMu.Lock()
-	if cancel, ok := h.cancelMonitors[txID]; ok {
-		cancel()
-		delete(h.cancelMonitors, txID)
-		logger.Info("✋ Đã yêu cầu dừng monitor giao dịch:", txID)
-	}
-	h.cancelMu.Unlock()
-	kq, err := h.service.GetTx(txID)
+	h.cancelMonitor(txID)
+	tx, err := h.query.GetTx(txID)
 	if err != nil {
 		logger.Error("fail in GetTx", err)
 		return
 	}
-	tx, ok := kq.(map[string]interface{})
-	if !ok {
-		logger.Error("Error when parse GetTx.")
-		return
-	}
-	status, ok := tx["status"].(uint8)
-	if !ok {
-		logger.Error("Error when parse status handleRequestUpdateTxStatus.")
-		return
-	}
-	reason, ok := tx["reason"].(string)
-	if !ok {
-		logger.Error("Error when parse reason handleRequestUpdateTxStatus.")
-		return
-	}
+	status := tx.Status
+	reason := tx.Reason
 
 	if status == 1 {
-		statusQuery := utils.UpdateStatus(txID)
+		charge, err := h.DB.Charges().Get(txID)
+		if err != nil {
+			logger.Error("không tìm thấy charge của giao dịch, không thể hoàn tất:", txID, err)
+			return
+		}
+		statusQuery := utils.UpdateStatus(txID, h.statusMID(charge.MID))
 		atTime := time.Now().Unix()
 		
-		if statusQuery == "success" || strings.Contains(statusQuery,"success"){
-			_,err := h.service.UpdateTxStatus(tokenId, txID, 2, uint64(atTime), "success")
-			if err != nil {
-				logger.Error("Error when UpdateTxStatus:",err)
+		if model.AcquirerStatus(utils.ParseStatusResponse(statusQuery)) == model.AcquirerApproved {
+			amount, ok := new(big.Int).SetString(charge.Amount, 10)
+			if !ok {
+				logger.Error("invalid charge amount:", charge.Amount)
+				return
+			}
+			if err := h.settleCharge(tokenId, txID, amount, common.HexToAddress(charge.Merchant)); err != nil {
+				logger.Error("Error when settle charge:", err)
 				return
 			}
 		} else {
@@ -402,21 +425,28 @@ func (h *CardHandler) handleTokenRequest(data string) {
 			logger.Error("Database connection is nil in handleTokenRequest")
 			return 
 		}
-		callmap := map[string]interface{}{
-			"key":  "token_" + hex.EncodeToString(tokenId[:]),
-			"data": string(encryptedCardData),
-		}
-		err = database.WriteValueStorage(callmap, h.DB)
+		err = h.DB.Tokens().SaveCard(tokenId, encryptedCardData)
 		if err != nil {
 			logger.Error("fail in save in leveldb handleTokenRequest:", err)
 			return
 		}
 		logger.Info("Saved token in db")
+		tokenInfo := model.TokenInfo{
+			TokenID:  hex.EncodeToString(tokenId[:]),
+			User:     user.Hex(),
+			Region:   region,
+			CardHash: hex.EncodeToString(cardHash[:]),
+			IssuedAt: time.Now().Unix(),
+			Contract: h.contract.Name,
+		}
+		if err := h.DB.Tokens().SaveInfo(tokenInfo); err != nil {
+			logger.Error("fail in save token info handleTokenRequest:", err)
+		}
 	// }
 
 }
 
-func (h *CardHandler) handleChargeRequest(data string) {
+func (h *CardHandler) handleChargeRequest(data string, requestTxHash string) {
 	start := time.Now() 
 	fmt.Println("handleChargeRequest")
 	result := make(map[string]interface{})
@@ -430,72 +460,130 @@ func (h *CardHandler) handleChargeRequest(data string) {
 		logger.Error("fail in parse tokenId:", err)
 		return
 	}
-	callmap := map[string]interface{}{
-		"key": "token_" + hex.EncodeToString(tokenId[:]),
+	user, _ := result["user"].(common.Address)
+	amount, ok := result["amount"].(*big.Int)
+	if !ok {
+		logger.Error("fail in parse amount:", err)
+		return
 	}
-	encryptedCardData, err := database.ReadValueStorage(callmap, h.DB)
-	if err != nil {
-		logger.Error("fail in get encryptedCardData in db:", err)
+	merchant, ok := result["merchant"].(common.Address)
+	if !ok {
+		logger.Error("fail in parse merchant:", err)
 		return
 	}
-	fmt.Println("encryptedCardData charge la:", string(encryptedCardData))
-	// Convert HEX string to bytes
-	serverPrivateKeyBytes, err := hex.DecodeString(h.ServerPrivateKey)
+	txID := utils.GenerateTxID()
+	h.createCharge(model.Charge{
+		TxID:          txID,
+		Contract:      h.contract.Name,
+		TokenID:       hex.EncodeToString(tokenId[:]),
+		User:          user.Hex(),
+		Merchant:      merchant.Hex(),
+		Amount:        amount.String(),
+		Status:        model.ChargeReceived,
+		RequestTxHash: requestTxHash,
+	})
+	card, err := h.loadCard(tokenId)
 	if err != nil {
-		logger.Error("Lỗi giải mã HEX: %v", err)
+		logger.Error("fail in load card ChargeRequest:", err)
+		h.transitionCharge(txID, model.ChargeFailed, "card data unavailable")
+		return
+	}
+	fmt.Println("card.CVV:", card.CVV)
+	merchantInfo, err := h.DB.Merchants().Get(merchant)
+	if errors.Is(err, database.ErrMerchantNotFound) {
+		logger.Warn("merchant chưa được đăng ký:", merchant.Hex())
+		h.declineCharge(tokenId, txID, "merchant not registered")
 		return
 	}
-	encyptedCard := encryptedCardData[65:]
-	clientPublicKey := encryptedCardData[:65]
-	iv := encyptedCard[:16]
-	token, err := utils.DecryptAESCBC(encyptedCard[16:], serverPrivateKeyBytes, clientPublicKey, iv)
 	if err != nil {
-		logger.Error("fail in decrypt token ChargeRequest:", err)
+		logger.Error("fail in get merchant:", err)
+		h.transitionCharge(txID, model.ChargeFailed, err.Error())
 		return
 	}
-	var card model.CardData
-	if err := json.Unmarshal(token, &card); err != nil {
-		logger.Error("❌ Parse card failed: %v", err)
+	if !merchantInfo.Enabled {
+		logger.Warn("merchant đang bị tắt:", merchant.Hex())
+		h.declineCharge(tokenId, txID, "merchant disabled")
 		return
 	}
-	fmt.Println("card.CVV:", card.CVV)
-	amount, ok := result["amount"].(*big.Int)
-	if !ok {
-		logger.Error("fail in parse amount:", err)
+	money, err := utils.TokenToMinorUnits(amount, h.config.TokenDecimals, h.config.ChargeCurrency, h.config.ChargeCurrencyExponent)
+	if err != nil {
+		logger.Error("fail in convert charge amount:", err)
+		h.declineCharge(tokenId, txID, "invalid amount")
 		return
 	}
-	merchant, ok := result["merchant"].(common.Address)
-	if !ok {
-		logger.Error("fail in parse merchant:", err)
+
+	tokenInfo, err := h.DB.Tokens().Info(tokenId)
+	if err != nil {
+		logger.Error("fail in get token info:", err)
+		h.transitionCharge(txID, model.ChargeFailed, err.Error())
 		return
 	}
+	decision := h.risk.Evaluate(risk.Charge{
+		TokenID:  tokenId,
+		CardHash: sha256.Sum256([]byte(card.CardNumber + card.ExpMonth + card.ExpYear)),
+		Token:    tokenInfo,
+		Merchant: merchantInfo,
+		Amount:   money,
+		At:       start,
+	})
+	switch decision.Decision {
+	case risk.Decline:
+		logger.Info("🚫 Risk engine từ chối giao dịch:", decision.Rule, decision.Reason)
+		h.declineCharge(tokenId, txID, decision.Reason)
+		return
+	case risk.Review:
+		logger.Info("⏸️ Risk engine giữ giao dịch chờ duyệt:", decision.Rule, decision.Reason)
+		h.holdCharge(tokenId, txID, amount, merchant, decision)
+		return
+	}
+	h.executeCharge(tokenId, txID, card, amount, money, merchant, merchantInfo, start)
+}
+
+// executeCharge gửi charge sang acquirer và cập nhật trạng thái lên contract
+func (h *CardHandler) executeCharge(
+	tokenId [32]byte,
+	txID string,
+	card model.CardData,
+	amount *big.Int,
+	money model.Money,
+	merchant common.Address,
+	merchantInfo model.Merchant,
+	start time.Time,
+) {
 	atTime := time.Now().Unix()
-	kq, err := utils.SendToThirdParty(card, amount, merchant, h.thirdPartyURL)
+	cardHash := sha256.Sum256([]byte(card.CardNumber + card.ExpMonth + card.ExpYear))
+	h.updateCharge(txID, func(charge *model.Charge) {
+		charge.CardHash = hex.EncodeToString(cardHash[:])
+		charge.MID = merchantInfo.MID
+		charge.Money = money
+		appendChargeEvent(charge, model.ChargePending, "sent to acquirer")
+	})
+	kq, err := utils.SendToThirdParty(card, txID, money, merchantInfo, h.thirdPartyURL)
+	if err != nil {
+		logger.Error("Error when SendToThirdParty:", err)
+	}
+	h.updateCharge(txID, func(charge *model.Charge) {
+		charge.AcquirerResponse = kq.Message
+	})
 	if kq.Status == "failed" && !strings.Contains(kq.Message, "Transaction failed, pending"){
 		logger.Info("❌ Giao dịch thất bại: %s", kq.Message)
+		h.failCharge(tokenId, cardHash, kq.TransactionID, kq.Message)
 
-		_,err = h.service.UpdateTxStatus(tokenId, kq.TransactionID, 0, uint64(atTime), kq.Message)
+	}else if kq.Status == "success"{
+		h.resetDeclines(tokenId, cardHash)
+		if err := h.settleCharge(tokenId, kq.TransactionID, amount, merchant); err != nil {
+			logger.Error("Error when settle charge:", err)
+			return
+		}
+	}else if kq.Status == "challenge required" && kq.Challenge != nil {
+		logger.Info("🔐 Acquirer yêu cầu xác thực chủ thẻ:", kq.Challenge.Type)
+		h.saveChallenge(*kq.Challenge, tokenId, cardHash, amount, merchant, merchantInfo.MID)
+		h.transitionCharge(txID, model.ChargeChallenge, kq.Challenge.Type)
+		_,err := h.service.UpdateTxStatus(tokenId, kq.TransactionID, 1, uint64(atTime), "challenge required")
 		if err != nil {
 			logger.Error("Error when UpdateTxStatus:",err)
 			return
 		}
-
-	}else if kq.Status == "success"{
-		// go func(){
-			_,err = h.service.UpdateTxStatus(tokenId, kq.TransactionID, 2, uint64(atTime), "success")
-			if err != nil {
-				logger.Error("Error when UpdateTxStatus:",err)
-				return
-			}	
-		// }()
-
-		// go func(){
-			_,err := h.service.MintUTXO(amount, merchant, kq.TransactionID)
-			if err != nil {
-				logger.Error("Error when MintUTXO:",err)
-				return
-			}	
-		// }()
 	}else{
 		// kq.Status == "being processed" || (kq.Status == "failed" && strings.Contains(kq.Message, "Transaction failed, pending") ){
 		logger.Info("⏳ Giao dịch đang xử lý...")
@@ -504,49 +592,34 @@ func (h *CardHandler) handleChargeRequest(data string) {
 			logger.Error("Error when UpdateTxStatus:",err)
 			return
 		}	
-		ctx, cancel := context.WithCancel(context.Background())
+		h.startMonitor(tokenId, kq.TransactionID, amount, merchant, merchantInfo.MID, start)
+	}
+}
 
-		h.cancelMu.Lock()
-		if oldCancel, ok := h.cancelMonitors[kq.TransactionID]; ok {
-			oldCancel() // hủy monitor cũ nếu có
-		}
-		h.cancelMonitors[kq.TransactionID] = cancel
-		h.cancelMu.Unlock()
-		go h.monitorTransaction(tokenId, ctx, kq.TransactionID, amount, merchant,start)
+// startMonitor chạy monitorTransaction cho giao dịch đang xử lý, huỷ monitor cũ cùng txID nếu có
+func (h *CardHandler) startMonitor(tokenId [32]byte, txID string, amount *big.Int, merchant common.Address, mID string, start time.Time) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	h.cancelMu.Lock()
+	if oldCancel, ok := h.cancelMonitors[txID]; ok {
+		oldCancel() // hủy monitor cũ nếu có
 	}
+	h.cancelMonitors[txID] = cancel
+	h.cancelMu.Unlock()
+	go h.monitorTransaction(tokenId, ctx, txID, amount, merchant, mID, start)
 }
-func (h *CardHandler) monitorTransaction(tokenId [32]byte, ctx context.Context, txID string, parentValue *big.Int, ownerPool common.Address,start time.Time) {
+func (h *CardHandler) monitorTransaction(tokenId [32]byte, ctx context.Context, txID string, parentValue *big.Int, ownerPool common.Address, mID string, start time.Time) {
 	for i := 0; i < 5; i++ {
 		select {
 		case <-ctx.Done():
 			logger.Info("🛑 Dừng kiểm tra giao dịch:", txID)
 			return
 		case <-time.After(1 * time.Second):
-			status := utils.UpdateStatus(txID)
-			atTime := time.Now().Unix()
-			if status == "success" || strings.Contains(status,"success"){
-				// kq, err := h.service.UpdateTxStatus(tokenId, txID, 2, uint64(atTime), "success")
-				// result, ok := kq.(bool)
-				// if err == nil && ok && result {
-				// 	h.service.MintUTXO(parentValue, ownerPool, txID)
-				// }
-				_,err := h.service.UpdateTxStatus(tokenId, txID, 2, uint64(atTime), "success")
-				if err != nil {
-					logger.Error("Error when UpdateTxStatus:",err)
-					return
-				}	
-				kq,err:= h.service.MintUTXO(parentValue, ownerPool, txID)
-				if err != nil {
-					logger.Error("Error when MintUTXO:",err)
-					return
-				}	
-				fmt.Println("Done",kq)
-				
-				_,err = h.service.GetPoolInfo(txID)
-				if err != nil {
-					logger.Error("Error when GetPoolInfo:",err)
-					return
-				}	
+			status := utils.UpdateStatus(txID, h.statusMID(mID))
+			if model.AcquirerStatus(utils.ParseStatusResponse(status)) == model.AcquirerApproved {
+				if err := h.settleCharge(tokenId, txID, parentValue, ownerPool); err != nil {
+					logger.Error("Error when settle charge:", err)
+				}
 				return
 			}
 			//co truong hop : transaction not exists (not in Unsettled) -> ma sau do giao dich la success-> tam comment lai
@@ -564,3 +637,168 @@ func (h *CardHandler) monitorTransaction(tokenId [32]byte, ctx context.Context,
 	}
 	logger.Info("❗ Hết thời gian kiểm tra.")
 }
+
+// declineCharge từ chối charge trước khi gửi sang acquirer và ghi trạng thái thất bại lên contract
+func (h *CardHandler) declineCharge(tokenId [32]byte, txID string, reason string) {
+	h.transitionCharge(txID, model.ChargeFailed, reason)
+	_, err := h.service.UpdateTxStatus(tokenId, txID, 0, uint64(time.Now().Unix()), reason)
+	if err != nil {
+		logger.Error("Error when UpdateTxStatus:", err)
+	}
+}
+
+// statusMID trả MID dùng khi truy vấn trạng thái, charge chưa có MID thì dùng DefaultMID
+func (h *CardHandler) statusMID(mID string) string {
+	if mID == "" {
+		return h.config.DefaultMID
+	}
+	return mID
+}
+
+// loadCard đọc và giải mã dữ liệu thẻ của token trong leveldb
+func (h *CardHandler) loadCard(tokenId [32]byte) (model.CardData, error) {
+	var card model.CardData
+	encryptedCardData, err := h.DB.Tokens().Card(tokenId)
+	if err != nil {
+		return card, fmt.Errorf("get encryptedCardData in db: %w", err)
+	}
+	serverPrivateKeyBytes, err := hex.DecodeString(h.ServerPrivateKey)
+	if err != nil {
+		return card, fmt.Errorf("decode server private key: %w", err)
+	}
+	if len(encryptedCardData) < 65+16 {
+		return card, fmt.Errorf("encryptedCardData too short")
+	}
+	encyptedCard := encryptedCardData[65:]
+	clientPublicKey := encryptedCardData[:65]
+	iv := encyptedCard[:16]
+	token, err := utils.DecryptAESCBC(encyptedCard[16:], serverPrivateKeyBytes, clientPublicKey, iv)
+	if err != nil {
+		return card, fmt.Errorf("decrypt token: %w", err)
+	}
+	if err := json.Unmarshal(token, &card); err != nil {
+		return card, fmt.Errorf("parse card: %w", err)
+	}
+	return card, nil
+}
+
+// holdCharge giữ charge chờ duyệt, trên contract giao dịch ở trạng thái being processed
+func (h *CardHandler) holdCharge(tokenId [32]byte, txID string, amount *big.Int, merchant common.Address, decision risk.Result) {
+	held := model.HeldCharge{
+		TxID:      txID,
+		TokenID:   hex.EncodeToString(tokenId[:]),
+		Merchant:  merchant.Hex(),
+		Amount:    amount.String(),
+		Rule:      decision.Rule,
+		Reason:    decision.Reason,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := h.DB.Charges().SaveHeld(held); err != nil {
+		logger.Error("fail in save held charge:", err)
+		return
+	}
+	h.transitionCharge(txID, model.ChargeHeld, decision.Reason)
+	_, err := h.service.UpdateTxStatus(tokenId, txID, 1, uint64(held.CreatedAt), "held for review")
+	if err != nil {
+		logger.Error("Error when UpdateTxStatus:", err)
+	}
+}
+
+// ErrHeldChargeReleasing là lỗi khi charge đang bị giữ đã có một lần duyệt khác đang chạy
+var ErrHeldChargeReleasing = errors.New("held charge is already being released")
+
+// ReleaseHeldCharge duyệt (approve=true) hoặc từ chối một charge đang bị giữ. Bản ghi held chỉ
+// bị xoá sau khi charge đã được gửi lại hoặc từ chối, lỗi ở bước chuẩn bị giữ nguyên bản ghi để duyệt lại.
+func (h *CardHandler) ReleaseHeldCharge(txID string, approve bool) error {
+	if owner := h.forTx(txID); owner != h {
+		return owner.ReleaseHeldCharge(txID, approve)
+	}
+	held, err := h.DB.Charges().GetHeld(txID)
+	if err != nil {
+		return err
+	}
+	h.chargeMu.Lock()
+	if h.releasing[txID] {
+		h.chargeMu.Unlock()
+		return ErrHeldChargeReleasing
+	}
+	h.releasing[txID] = true
+	h.chargeMu.Unlock()
+	release := func() {
+		h.chargeMu.Lock()
+		delete(h.releasing, txID)
+		h.chargeMu.Unlock()
+	}
+
+	// Bản ghi held còn sót lại khi charge đã được xử lý (vd: xoá lỗi sau lần duyệt trước)
+	if charge, err := h.DB.Charges().Get(txID); err == nil && charge.Status != model.ChargeHeld {
+		release()
+		h.deleteHeld(txID)
+		return fmt.Errorf("%w: charge is %s", database.ErrHeldChargeNotFound, charge.Status)
+	}
+
+	var tokenId [32]byte
+	copy(tokenId[:], e_common.FromHex(held.TokenID))
+	if !approve {
+		defer release()
+		_, err := h.service.UpdateTxStatus(tokenId, txID, 0, uint64(time.Now().Unix()), "declined after review")
+		if err != nil {
+			return err
+		}
+		h.transitionCharge(txID, model.ChargeFailed, "declined after review")
+		h.deleteHeld(txID)
+		return nil
+	}
+
+	card, err := h.loadCard(tokenId)
+	if err != nil {
+		release()
+		return err
+	}
+	merchant := common.HexToAddress(held.Merchant)
+	merchantInfo, err := h.DB.Merchants().Get(merchant)
+	if err != nil {
+		release()
+		return err
+	}
+	amount, ok := new(big.Int).SetString(held.Amount, 10)
+	if !ok {
+		release()
+		return fmt.Errorf("invalid held amount %q", held.Amount)
+	}
+	money, err := utils.TokenToMinorUnits(amount, h.config.TokenDecimals, h.config.ChargeCurrency, h.config.ChargeCurrencyExponent)
+	if err != nil {
+		release()
+		return err
+	}
+	go func() {
+		defer release()
+		h.executeCharge(tokenId, txID, card, amount, money, merchant, merchantInfo, time.Now())
+		h.deleteHeld(txID)
+	}()
+	return nil
+}
+
+func (h *CardHandler) deleteHeld(txID string) {
+	if err := h.DB.Charges().DeleteHeld(txID); err != nil {
+		logger.Error("fail in delete held charge:", err)
+	}
+}
+
+// ApplyHeldDecisions áp dụng các quyết định duyệt ghi bằng lệnh `cardvisa held` trong lúc service dừng
+func (h *CardHandler) ApplyHeldDecisions() {
+	charges, err := h.DB.Charges().ListHeld()
+	if err != nil {
+		logger.Error("fail in list held charges:", err)
+		return
+	}
+	for _, held := range charges {
+		if held.Decision == "" {
+			continue
+		}
+		logger.Info("🔓 Áp dụng quyết định cho charge bị giữ:", held.TxID, held.Decision)
+		if err := h.ReleaseHeldCharge(held.TxID, held.Decision == model.HeldApprove); err != nil {
+			logger.Error("fail in release held charge:", held.TxID, err)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package network
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	"github.com/meta-node-blockchain/cardvisa/internal/services/fake"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+)
+
+var (
+	testUser     = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testMerchant = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	testTokenId  = [32]byte{1, 2, 3}
+	testCard     = model.CardData{CardNumber: "4111111111111111", ExpMonth: "12", ExpYear: "2030", CVV: "123"}
+)
+
+// handlerEnv là CardHandler nối với Service, Query, Gateway giả và store trong bộ nhớ
+type handlerEnv struct {
+	handler  *CardHandler
+	service  *fake.Service
+	query    *fake.Query
+	acquirer *fake.Gateway
+	db       database.Store
+	cardABI  *abi.ABI
+}
+
+func newHandlerEnv(t *testing.T, risk config.RiskConfig) *handlerEnv {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("..", "..", "abi", "card.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardABI, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &handlerEnv{
+		query:    fake.NewQuery(),
+		acquirer: fake.NewGateway(fake.OutcomeSuccess),
+		db:       database.OpenMemory(),
+		cardABI:  &cardABI,
+	}
+	env.service = fake.NewService(env.query)
+	t.Cleanup(func() {
+		env.acquirer.Close()
+		env.db.Close()
+	})
+
+	cfg := &config.AppConfig{
+		ChargeCurrency: "VND",
+		DefaultMID:     "pos123",
+		Risk:           risk,
+	}
+	env.handler = NewCardEventHandler(
+		cfg,
+		config.CardContractConfig{Name: config.DefaultContractName},
+		env.service,
+		env.query,
+		&cardABI,
+		hex.EncodeToString(crypto.FromECDSA(serverKey)),
+		env.db,
+		env.acquirer.ChargeURL(),
+		hex.EncodeToString(crypto.FromECDSAPub(&serverKey.PublicKey)),
+		make(chan model.EventLog, 10),
+	)
+
+	encrypted := encryptCard(t, serverKey.PublicKey, testCard)
+	if err := env.db.Tokens().SaveCard(testTokenId, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	err = env.db.Tokens().SaveInfo(model.TokenInfo{
+		TokenID:  hex.EncodeToString(testTokenId[:]),
+		User:     testUser.Hex(),
+		Region:   "VN",
+		IssuedAt: time.Now().Add(-time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = env.db.Merchants().Save(model.Merchant{
+		Address: testMerchant.Hex(),
+		MID:     "mid-1",
+		Enabled: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// encryptCard mã hoá thẻ như client: pubkey client (65 byte) || iv (16 byte) || AES-CBC(json thẻ)
+func encryptCard(t *testing.T, serverPub ecdsa.PublicKey, card model.CardData) []byte {
+	t.Helper()
+	clientKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sharedHex, err := utils.ECDHSharedSecretHex(crypto.FromECDSA(clientKey), crypto.FromECDSAPub(&serverPub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := hex.DecodeString(sharedHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := json.Marshal(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iv := make([]byte, 16)
+	if _, err := rand.Read(iv); err != nil {
+		t.Fatal(err)
+	}
+	cipherText, err := utils.EncryptAESCBC(shared, plain, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := append(crypto.FromECDSAPub(&clientKey.PublicKey), iv...)
+	return append(out, cipherText...)
+}
+
+// charge phát event ChargeRequest như contract và trả về txID handler đã tạo
+func (env *handlerEnv) charge(t *testing.T, amount int64) string {
+	t.Helper()
+	event := env.cardABI.Events["ChargeRequest"]
+	data, err := event.Inputs.NonIndexed().Pack(testUser, testTokenId, testMerchant, big.NewInt(amount))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.handler.HandleConnectSmartContract(model.EventLog{
+		Topics:          []string{event.ID.String()},
+		Data:            hexutil.Encode(data),
+		TransactionHash: "0xrequest",
+	})
+	charges, err := env.db.Charges().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(charges) != 1 {
+		t.Fatalf("ledger có %d charge, want 1", len(charges))
+	}
+	return charges[0].TxID
+}
+
+func (env *handlerEnv) chargeStatus(t *testing.T, txID string) string {
+	t.Helper()
+	charge, err := env.db.Charges().Get(txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return charge.Status
+}
+
+// txStatuses trả về status của các lời gọi UpdateTxStatus theo thứ tự
+func (env *handlerEnv) txStatuses() []uint8 {
+	var out []uint8
+	for _, call := range env.service.CallsTo("UpdateTxStatus") {
+		out = append(out, call.Args[2].(uint8))
+	}
+	return out
+}
+
+func TestChargeApproved(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	txID := env.charge(t, 50_000)
+
+	if status := env.chargeStatus(t, txID); status != model.ChargeSuccess {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeSuccess)
+	}
+	requests := env.acquirer.Charges()
+	if len(requests) != 1 || requests[0].TxID != txID || requests[0].Amount != 50_000 || requests[0].MID != "mid-1" {
+		t.Fatalf("acquirer requests %+v", requests)
+	}
+	if got := env.txStatuses(); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("UpdateTxStatus statuses %v, want [2]", got)
+	}
+	if n := len(env.service.CallsTo("MintUTXO")); n != 1 {
+		t.Fatalf("MintUTXO called %d times, want 1", n)
+	}
+	pool, _ := env.query.GetPoolInfo(txID)
+	if pool.OwnerPool != testMerchant || pool.ParentValue.Cmp(big.NewInt(50_000)) != 0 {
+		t.Fatalf("pool %+v", pool)
+	}
+	saga, err := env.db.Settlements().Get(txID)
+	if err != nil || !saga.Done() {
+		t.Fatalf("settlement %+v, err %v", saga, err)
+	}
+}
+
+func TestChargeDeclinedByAcquirer(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	env.acquirer.Script(fake.OutcomeFailed)
+	env.acquirer.Decline("Do not honor")
+	txID := env.charge(t, 50_000)
+
+	charge, err := env.db.Charges().Get(txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if charge.Status != model.ChargeFailed || charge.Reason != "Do not honor" {
+		t.Fatalf("charge %s/%q, want failed/Do not honor", charge.Status, charge.Reason)
+	}
+	if got := env.txStatuses(); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("UpdateTxStatus statuses %v, want [0]", got)
+	}
+	if n := len(env.service.CallsTo("MintUTXO")); n != 0 {
+		t.Fatalf("MintUTXO called %d times for a declined charge", n)
+	}
+}
+
+func TestChargeDeclinedBeforeAcquirer(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	if err := env.db.Merchants().Delete(testMerchant); err != nil {
+		t.Fatal(err)
+	}
+	txID := env.charge(t, 50_000)
+
+	if status := env.chargeStatus(t, txID); status != model.ChargeFailed {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeFailed)
+	}
+	if n := len(env.acquirer.Charges()); n != 0 {
+		t.Fatalf("acquirer received %d requests for an unregistered merchant", n)
+	}
+	if got := env.txStatuses(); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("UpdateTxStatus statuses %v, want [0]", got)
+	}
+}
+
+func TestChargeHeldThenApproved(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{Enabled: true, ReviewAmountAbove: 10_000})
+	txID := env.charge(t, 50_000)
+
+	if status := env.chargeStatus(t, txID); status != model.ChargeHeld {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeHeld)
+	}
+	if _, err := env.db.Charges().GetHeld(txID); err != nil {
+		t.Fatalf("held record: %v", err)
+	}
+	if n := len(env.acquirer.Charges()); n != 0 {
+		t.Fatalf("held charge was sent to the acquirer %d times", n)
+	}
+	if got := env.txStatuses(); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("UpdateTxStatus statuses %v, want [1]", got)
+	}
+
+	if err := env.handler.ReleaseHeldCharge(txID, true); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		_, err := env.db.Charges().GetHeld(txID)
+		return errors.Is(err, database.ErrHeldChargeNotFound)
+	})
+	if status := env.chargeStatus(t, txID); status != model.ChargeSuccess {
+		t.Fatalf("charge status %q after approve, want %q", status, model.ChargeSuccess)
+	}
+	if n := len(env.acquirer.Charges()); n != 1 {
+		t.Fatalf("acquirer received %d requests after approve, want 1", n)
+	}
+}
+
+func TestChargeHeldThenDeclined(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{Enabled: true, ReviewAmountAbove: 10_000})
+	txID := env.charge(t, 50_000)
+
+	if err := env.handler.ReleaseHeldCharge(txID, false); err != nil {
+		t.Fatal(err)
+	}
+	if status := env.chargeStatus(t, txID); status != model.ChargeFailed {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeFailed)
+	}
+	if _, err := env.db.Charges().GetHeld(txID); !errors.Is(err, database.ErrHeldChargeNotFound) {
+		t.Fatalf("held record still present: %v", err)
+	}
+	if got := env.txStatuses(); len(got) != 2 || got[1] != 0 {
+		t.Fatalf("UpdateTxStatus statuses %v, want [1 0]", got)
+	}
+	if err := env.handler.ReleaseHeldCharge(txID, true); !errors.Is(err, database.ErrHeldChargeNotFound) {
+		t.Fatalf("second release err %v, want ErrHeldChargeNotFound", err)
+	}
+}
+
+func TestSettleRetriesMintAfterFailure(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	env.service.Fail("MintUTXO", errors.New("rpc unavailable"))
+	txID := env.charge(t, 50_000)
+
+	saga, err := env.db.Settlements().Get(txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saga.Step != model.SettlementStatusUpdated || saga.Mints != 1 || saga.NeedsReview {
+		t.Fatalf("saga after failed mint %+v", saga)
+	}
+	if err := env.handler.runSettlement(saga); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(env.service.CallsTo("MintUTXO")); n != 2 {
+		t.Fatalf("MintUTXO called %d times, want 2", n)
+	}
+	if status := env.chargeStatus(t, txID); status != model.ChargeSuccess {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeSuccess)
+	}
+}
+
+func TestSettleSkipsMintWhenPoolExists(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	env.service.OnCall(func(call fake.Call) error {
+		if call.Method != "MintUTXO" {
+			return nil
+		}
+		// Transaction vào block nhưng receipt không về kịp
+		txID := call.Args[2].(string)
+		env.query.SetPool(txID, model.PoolInfo{
+			OwnerPool:   testMerchant,
+			ParentValue: big.NewInt(50_000),
+		})
+		return errors.New("receipt lost")
+	})
+	txID := env.charge(t, 50_000)
+	env.service.OnCall(nil)
+
+	saga, err := env.db.Settlements().Get(txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := env.handler.runSettlement(saga); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(env.service.CallsTo("MintUTXO")); n != 1 {
+		t.Fatalf("MintUTXO called %d times, want 1", n)
+	}
+	if status := env.chargeStatus(t, txID); status != model.ChargeSuccess {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeSuccess)
+	}
+}
+
+func TestSettleUnconfirmedMintNeedsReview(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	env.service.Fail("MintUTXO", services.ErrTxUnconfirmed)
+	txID := env.charge(t, 50_000)
+
+	saga, err := env.db.Settlements().Get(txID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !saga.NeedsReview {
+		t.Fatalf("saga after unconfirmed mint %+v, want NeedsReview", saga)
+	}
+	if status := env.chargeStatus(t, txID); status != model.ChargeSettling {
+		t.Fatalf("charge status %q, want %q", status, model.ChargeSettling)
+	}
+}
+
+func waitFor(t *testing.T, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEventMarkedSeenAfterHandling(t *testing.T) {
+	env := newHandlerEnv(t, config.RiskConfig{})
+	event := env.cardABI.Events["ChargeRequest"]
+	data, err := event.Inputs.NonIndexed().Pack(testUser, testTokenId, testMerchant, big.NewInt(50_000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := model.EventLog{
+		BlockNumber:     "0x10",
+		LogIndex:        "0x0",
+		Topics:          []string{event.ID.String()},
+		Data:            hexutil.Encode(data),
+		TransactionHash: "0xrequest",
+	}
+	var seenDuringHandling bool
+	env.service.OnCall(func(call fake.Call) error {
+		if call.Method == "MintUTXO" {
+			seenDuringHandling, _ = env.db.Events().Seen(log)
+		}
+		return nil
+	})
+	env.handler.HandleConnectSmartContract(log)
+
+	if seenDuringHandling {
+		t.Fatal("event marked seen before the handler finished")
+	}
+	if seen, err := env.db.Events().Seen(log); !seen || err != nil {
+		t.Fatalf("Seen after handling = %v, err %v", seen, err)
+	}
+}
@@ -0,0 +1,84 @@
+package network
+
+import (
+	"errors"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// createCharge ghi bản ghi ledger đầu tiên của một ChargeRequest
+func (h *CardHandler) createCharge(charge model.Charge) {
+	now := time.Now().Unix()
+	charge.CreatedAt = now
+	charge.UpdatedAt = now
+	charge.History = []model.ChargeEvent{{Status: charge.Status, Reason: charge.Reason, At: now}}
+	h.chargeMu.Lock()
+	defer h.chargeMu.Unlock()
+	if err := h.DB.Charges().Save(charge); err != nil {
+		logger.Error("fail in save charge:", err)
+	}
+}
+
+// updateCharge đọc, sửa và ghi lại charge dưới chargeMu. Charge tạo trước khi có ledger bị bỏ qua.
+func (h *CardHandler) updateCharge(txID string, update func(charge *model.Charge)) {
+	h.chargeMu.Lock()
+	defer h.chargeMu.Unlock()
+	charge, err := h.DB.Charges().Get(txID)
+	if err != nil {
+		if !errors.Is(err, database.ErrChargeNotFound) {
+			logger.Error("fail in get charge:", err)
+		}
+		return
+	}
+	update(&charge)
+	charge.UpdatedAt = time.Now().Unix()
+	if err := h.DB.Charges().Save(charge); err != nil {
+		logger.Error("fail in save charge:", err)
+	}
+}
+
+func appendChargeEvent(charge *model.Charge, status string, reason string) {
+	charge.Status = status
+	charge.Reason = reason
+	charge.History = append(charge.History, model.ChargeEvent{
+		Status: status,
+		Reason: reason,
+		At:     time.Now().Unix(),
+	})
+}
+
+// transitionCharge đổi trạng thái charge và ghi vào lịch sử
+func (h *CardHandler) transitionCharge(txID string, status string, reason string) {
+	h.updateCharge(txID, func(charge *model.Charge) {
+		appendChargeEvent(charge, status, reason)
+	})
+}
+
+// claimCharge chuyển charge sang trạng thái next nếu nó chưa được hoàn tất bởi luồng khác.
+// Charge tạo trước khi có ledger vẫn được xử lý như cũ.
+func (h *CardHandler) claimCharge(txID string, next string, reason string) bool {
+	h.chargeMu.Lock()
+	defer h.chargeMu.Unlock()
+	charge, err := h.DB.Charges().Get(txID)
+	if errors.Is(err, database.ErrChargeNotFound) {
+		return true
+	}
+	if err != nil {
+		logger.Error("fail in get charge:", err)
+		return false
+	}
+	if charge.Final() {
+		logger.Info("charge đã được xử lý:", txID, charge.Status)
+		return false
+	}
+	appendChargeEvent(&charge, next, reason)
+	charge.UpdatedAt = time.Now().Unix()
+	if err := h.DB.Charges().Save(charge); err != nil {
+		logger.Error("fail in save charge:", err)
+		return false
+	}
+	return true
+}
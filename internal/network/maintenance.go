@@ -0,0 +1,127 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+var errCleanUsageRunning = errors.New("cleanUsage is already running")
+
+// RunCleanUsageScheduler gọi cleanUsage theo CleanUsage.Interval, tính từ lần chạy cuối đã lưu
+// để restart service không làm job chạy lại ngay
+func (h *CardHandler) RunCleanUsageScheduler() {
+	cfg := h.config.CleanUsage
+	if !cfg.Enabled {
+		return
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	for {
+		state, err := h.DB.Maintenance().CleanUsageState(h.contract.Name)
+		if err != nil {
+			logger.Error("cleanUsage: fail in load state:", err)
+		}
+		if wait := time.Until(time.Unix(state.LastRunAt, 0).Add(interval)); wait > 0 {
+			time.Sleep(wait)
+			continue
+		}
+		if _, err := h.CleanUsage(time.Now()); err != nil {
+			logger.Error("cleanUsage: run failed:", err)
+		}
+	}
+}
+
+// CleanUsage dọn usage cũ hơn now-Retention. beforeTimestamp được dời dần từ LastCutoff
+// theo BatchWindow, tối đa MaxBatches transaction mỗi lần chạy; phần còn lại để lần sau.
+// Lần chạy đầu tiên chưa có LastCutoff nên batch đầu dọn luôn mọi usage cũ hơn nó.
+func (h *CardHandler) CleanUsage(now time.Time) (model.CleanUsageState, error) {
+	h.cleanMu.Lock()
+	if h.cleaning {
+		h.cleanMu.Unlock()
+		return model.CleanUsageState{}, errCleanUsageRunning
+	}
+	h.cleaning = true
+	h.cleanMu.Unlock()
+	defer func() {
+		h.cleanMu.Lock()
+		h.cleaning = false
+		h.cleanMu.Unlock()
+	}()
+
+	cfg := h.config.CleanUsage
+	state, err := h.DB.Maintenance().CleanUsageState(h.contract.Name)
+	if err != nil {
+		return state, err
+	}
+	window := int64(cfg.BatchWindow / time.Second)
+	if window <= 0 {
+		window = int64(6 * time.Hour / time.Second)
+	}
+	maxBatches := cfg.MaxBatches
+	if maxBatches <= 0 {
+		maxBatches = 1
+	}
+	target := now.Add(-cfg.Retention).Unix()
+	cursor := state.LastCutoff
+	if cursor == 0 {
+		cursor = target - window*int64(maxBatches)
+	}
+
+	state.LastRunAt = now.Unix()
+	state.Batches = 0
+	state.LastError = ""
+	for cursor < target && state.Batches < maxBatches {
+		cutoff := cursor + window
+		if cutoff > target {
+			cutoff = target
+		}
+		if err := h.cleanUsageBatch(cutoff); err != nil {
+			state.LastError = err.Error()
+			break
+		}
+		cursor = cutoff
+		state.LastCutoff = cutoff
+		state.Batches++
+	}
+	if state.LastError == "" {
+		state.LastSuccessAt = now.Unix()
+		logger.Info("🧹 cleanUsage done", h.contract.Name, "cutoff:", state.LastCutoff, "batches:", state.Batches)
+	}
+	if err := h.DB.Maintenance().SaveCleanUsageState(h.contract.Name, state); err != nil {
+		logger.Error("cleanUsage: fail in save state:", err)
+	}
+	if state.LastError != "" {
+		return state, errors.New(state.LastError)
+	}
+	return state, nil
+}
+
+// cleanUsageBatch khoá contract (cleanUsage yêu cầu isLocked), clean rồi mở khoá ngay để
+// charge không bị chặn lâu. Contract đã bị admin khoá từ trước thì giữ nguyên trạng thái khoá.
+func (h *CardHandler) cleanUsageBatch(cutoff int64) (err error) {
+	wasLocked, err := h.query.IsLocked()
+	if err != nil {
+		return fmt.Errorf("read isLocked: %w", err)
+	}
+	if !wasLocked {
+		if _, err := h.service.SetLock(true); err != nil {
+			return fmt.Errorf("setLock(true): %w", err)
+		}
+		defer func() {
+			if _, unlockErr := h.service.SetLock(false); unlockErr != nil {
+				logger.Error("⚠️ cleanUsage: contract vẫn đang bị khoá, cần setLock(false) bằng tay:", unlockErr)
+				err = errors.Join(err, fmt.Errorf("setLock(false): %w", unlockErr))
+			}
+		}()
+	}
+	if _, err := h.service.CleanUsage(uint64(cutoff)); err != nil {
+		return fmt.Errorf("cleanUsage(%d): %w", cutoff, err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package network
+
+import (
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+)
+
+// MaskedCard trả về thông tin thẻ đã che của token, không bao giờ trả PAN đầy đủ hay CVV
+func (h *CardHandler) MaskedCard(tokenId [32]byte) (model.MaskedCard, error) {
+	card, err := h.loadCard(tokenId)
+	if err != nil {
+		return model.MaskedCard{}, err
+	}
+	return model.MaskedCard{
+		CardNumber: utils.MaskCardNumber(card.CardNumber),
+		ExpMonth:   card.ExpMonth,
+		ExpYear:    card.ExpYear,
+	}, nil
+}
+
+// ChainTxStatus đọc trạng thái giao dịch trên contract (getTx)
+func (h *CardHandler) ChainTxStatus(txID string) (model.TxStatus, error) {
+	return h.forTx(txID).query.GetTx(txID)
+}
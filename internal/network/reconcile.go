@@ -0,0 +1,241 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	e_common "github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/database"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/utils"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// Reconcile đối chiếu file settlement của acquirer với charge đã lưu và trạng thái on-chain
+// (getTx, getPoolInfo). Charge chưa hoàn tất nhưng không có trong file cũng được kiểm tra
+// để phát hiện giao dịch bị kẹt ở BEING_PROCESSED. repair=true thì tự sửa các lỗi sửa được.
+func (h *CardHandler) Reconcile(records []model.SettlementRecord, source string, repair bool) (model.ReconcileReport, error) {
+	report := model.ReconcileReport{
+		Source:      source,
+		GeneratedAt: time.Now().Unix(),
+		Mismatches:  []model.Mismatch{},
+	}
+	charges, err := h.DB.Charges().List()
+	if err != nil {
+		return report, err
+	}
+	byTxID := make(map[string]model.Charge, len(charges))
+	for _, charge := range charges {
+		byTxID[charge.TxID] = charge
+	}
+
+	seen := make(map[string]bool, len(records))
+	for i := range records {
+		rec := records[i]
+		seen[rec.TxID] = true
+		report.Checked++
+		charge, ok := byTxID[rec.TxID]
+		if !ok {
+			report.Mismatches = append(report.Mismatches, model.Mismatch{
+				TxID:   rec.TxID,
+				Kind:   model.MismatchMissingLocal,
+				Detail: fmt.Sprintf("acquirer reports %s but no local charge", rec.Status),
+			})
+			continue
+		}
+		report.Mismatches = append(report.Mismatches, h.owner(charge).reconcileCharge(charge, &rec, repair)...)
+	}
+	for _, charge := range charges {
+		if seen[charge.TxID] || charge.Status == model.ChargeSuccess || charge.Status == model.ChargeFailed {
+			continue
+		}
+		report.Checked++
+		report.Mismatches = append(report.Mismatches, h.owner(charge).reconcileCharge(charge, nil, repair)...)
+	}
+	return report, nil
+}
+
+func (h *CardHandler) reconcileCharge(charge model.Charge, rec *model.SettlementRecord, repair bool) []model.Mismatch {
+	mismatches := []model.Mismatch{}
+	add := func(kind string, detail string, fix func() error) {
+		m := model.Mismatch{TxID: charge.TxID, Kind: kind, Detail: detail}
+		if repair && fix != nil {
+			if err := fix(); err != nil {
+				m.RepairError = err.Error()
+			} else {
+				m.Repaired = true
+			}
+		}
+		mismatches = append(mismatches, m)
+	}
+
+	var tokenId [32]byte
+	copy(tokenId[:], e_common.FromHex(charge.TokenID))
+	merchant := common.HexToAddress(charge.Merchant)
+	amount, ok := new(big.Int).SetString(charge.Amount, 10)
+	if !ok {
+		add(model.MismatchAmount, fmt.Sprintf("invalid local amount %q", charge.Amount), nil)
+		return mismatches
+	}
+
+	tx, err := h.query.GetTx(charge.TxID)
+	if err != nil {
+		logger.Error("reconcile: fail in GetTx", charge.TxID, err)
+		return mismatches
+	}
+	pool, err := h.query.GetPoolInfo(charge.TxID)
+	if err != nil {
+		logger.Error("reconcile: fail in GetPoolInfo", charge.TxID, err)
+		return mismatches
+	}
+	minted := pool.OwnerPool != (common.Address{})
+
+	// MintUTXO ghi đè pool của txID, nên pool on-chain khác pool mà saga đã mint nghĩa là
+	// có một lần mint khác cho cùng giao dịch
+	if saga, err := h.DB.Settlements().Get(charge.TxID); err == nil && minted && saga.Pool != "" &&
+		!strings.EqualFold(pool.Pool.Hex(), saga.Pool) {
+		add(model.MismatchDoubleMint, fmt.Sprintf("on-chain pool %s, settlement minted %s (%d MintUTXO sent)", pool.Pool.Hex(), saga.Pool, saga.Mints), nil)
+	}
+	if minted && (pool.ParentValue == nil || pool.ParentValue.Cmp(amount) != 0) {
+		add(model.MismatchAmount, fmt.Sprintf("pool value %v, charge amount %s", pool.ParentValue, charge.Amount), nil)
+	}
+
+	if rec != nil {
+		if rec.Amount != charge.Money.Amount || (rec.Currency != "" && !strings.EqualFold(rec.Currency, charge.Money.Currency)) {
+			add(model.MismatchAmount, fmt.Sprintf("acquirer %d %s, local %s", rec.Amount, rec.Currency, charge.Money), nil)
+		}
+		switch model.NormalizeAcquirerStatus(rec.Status) {
+		case model.AcquirerApproved:
+			if !minted {
+				add(model.MismatchMissingMint, "acquirer settled but no pool on chain", func() error {
+					return h.repairMint(charge, tokenId, amount, merchant)
+				})
+			} else if tx.Status != 2 {
+				add(model.MismatchStatus, fmt.Sprintf("acquirer settled, on-chain status %d", tx.Status), func() error {
+					_, err := h.service.UpdateTxStatus(tokenId, charge.TxID, 2, uint64(time.Now().Unix()), "success")
+					return err
+				})
+			}
+			return mismatches
+		case model.AcquirerDeclined:
+			if minted {
+				add(model.MismatchUnpaidMint, "acquirer failed but pool minted on chain", nil)
+			} else if tx.Status != 0 {
+				add(model.MismatchStatus, fmt.Sprintf("acquirer failed, on-chain status %d", tx.Status), func() error {
+					_, err := h.service.UpdateTxStatus(tokenId, charge.TxID, 0, uint64(time.Now().Unix()), rec.Status)
+					if err == nil {
+						h.transitionCharge(charge.TxID, model.ChargeFailed, "reconcile: "+rec.Status)
+					}
+					return err
+				})
+			}
+			return mismatches
+		case model.AcquirerPending:
+		default:
+			add(model.MismatchUnknownStatus, fmt.Sprintf("unknown acquirer status %q", rec.Status), nil)
+			return mismatches
+		}
+	}
+
+	stuckAfter := h.config.Reconcile.StuckAfter
+	// Charge đang chờ duyệt rủi ro hoặc chờ chủ thẻ xác thực cũng ở status 1 nhưng không bị kẹt
+	waiting := charge.Status == model.ChargeHeld || charge.Status == model.ChargeChallenge
+	if tx.Status == 1 && !waiting && stuckAfter > 0 && time.Since(time.Unix(charge.CreatedAt, 0)) > stuckAfter {
+		add(model.MismatchStuckProcessing, fmt.Sprintf("being processed since %s", time.Unix(charge.CreatedAt, 0).Format(time.RFC3339)), func() error {
+			h.startMonitor(tokenId, charge.TxID, amount, merchant, charge.MID, time.Now())
+			return nil
+		})
+	}
+	return mismatches
+}
+
+// repairMint chạy lại settlement saga từ bước mint cho giao dịch acquirer đã settle.
+// Saga đang chờ xử lý tay được gỡ NeedsReview và ghi lại là do reconcile gỡ.
+func (h *CardHandler) repairMint(charge model.Charge, tokenId [32]byte, amount *big.Int, merchant common.Address) error {
+	saga, err := h.DB.Settlements().Get(charge.TxID)
+	if errors.Is(err, database.ErrSettlementNotFound) {
+		h.transitionCharge(charge.TxID, model.ChargePending, "reconcile: missing mint")
+		return h.settleCharge(tokenId, charge.TxID, amount, merchant)
+	}
+	if err != nil {
+		return err
+	}
+	if saga.Step == model.SettlementUTXOMinted || saga.Step == model.SettlementPoolVerified {
+		saga.Step = model.SettlementStatusUpdated
+	}
+	if saga.NeedsReview {
+		saga.NeedsReview = false
+		saga.ReviewedBy = "reconcile"
+		saga.ReviewedAt = time.Now().Unix()
+		saga.ReviewNote = "auto-repair: acquirer settled but no pool on chain"
+	}
+	if err := h.DB.Settlements().Save(saga); err != nil {
+		return err
+	}
+	return h.runSettlement(saga)
+}
+
+// RunReconcileScheduler định kỳ xử lý các file settlement trong Reconcile.InboxDir,
+// ghi báo cáo vào Reconcile.ReportDir và chuyển file đã xử lý vào InboxDir/processed.
+func (h *CardHandler) RunReconcileScheduler() {
+	cfg := h.config.Reconcile
+	if !cfg.Enabled || cfg.InboxDir == "" {
+		return
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	processedDir := filepath.Join(cfg.InboxDir, "processed")
+	for {
+		entries, err := os.ReadDir(cfg.InboxDir)
+		if err != nil {
+			logger.Error("reconcile: fail in read inbox:", err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(cfg.InboxDir, entry.Name())
+			if err := h.reconcileFile(path, cfg.ReportDir, cfg.AutoRepair); err != nil {
+				logger.Error("reconcile: fail in process", path, err)
+				continue
+			}
+			if err := os.MkdirAll(processedDir, 0o755); err == nil {
+				os.Rename(path, filepath.Join(processedDir, entry.Name()))
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (h *CardHandler) reconcileFile(path string, reportDir string, repair bool) error {
+	records, err := utils.ParseSettlementFile(path)
+	if err != nil {
+		return err
+	}
+	report, err := h.Reconcile(records, filepath.Base(path), repair)
+	if err != nil {
+		return err
+	}
+	logger.Info("📊 Reconcile", report.Source, "checked:", report.Checked, "mismatches:", len(report.Mismatches))
+	if reportDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(reportDir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.%s.report.json", filepath.Base(path), time.Unix(report.GeneratedAt, 0).Format("20060102T150405"))
+	return os.WriteFile(filepath.Join(reportDir, name), data, 0o644)
+}
@@ -0,0 +1,200 @@
+package network
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	e_common "github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// Số lần getPoolInfo trả pool rỗng sau MintUTXO trước khi saga dừng chờ xử lý tay
+const settlementPoolChecks = 10
+
+// cancelMonitor dừng monitorTransaction đang chạy của giao dịch nếu có
+func (h *CardHandler) cancelMonitor(txID string) {
+	h.cancelMu.Lock()
+	defer h.cancelMu.Unlock()
+	if cancel, ok := h.cancelMonitors[txID]; ok {
+		cancel()
+		delete(h.cancelMonitors, txID)
+		logger.Info("✋ Đã yêu cầu dừng monitor giao dịch:", txID)
+	}
+}
+
+// settleCharge hoàn tất giao dịch thành công: mọi luồng (executor, monitor, webhook,
+// RequestUpdateTxStatus) đều đi qua đây để chỉ một luồng claim và chạy settlement saga.
+func (h *CardHandler) settleCharge(tokenId [32]byte, txID string, amount *big.Int, merchant common.Address) error {
+	if !h.claimCharge(txID, model.ChargeSettling, "acquirer confirmed") {
+		return nil
+	}
+	return h.startSettlement(tokenId, txID, amount, merchant)
+}
+
+// startSettlement tạo settlement saga cho charge đã claim và chạy nó; nếu một bước lỗi,
+// saga được giữ lại trong leveldb để ResumeSettlements retry. Không lưu được saga thì đưa charge
+// về pending để luồng hoàn tất khác (monitor, webhook, reconcile) có thể thử lại.
+func (h *CardHandler) startSettlement(tokenId [32]byte, txID string, amount *big.Int, merchant common.Address) error {
+	now := time.Now().Unix()
+	saga := model.Settlement{
+		TxID:      txID,
+		TokenID:   hex.EncodeToString(tokenId[:]),
+		Merchant:  merchant.Hex(),
+		Amount:    amount.String(),
+		Step:      model.SettlementGatewayConfirmed,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := h.DB.Settlements().Save(saga); err != nil {
+		h.transitionCharge(txID, model.ChargePending, "save settlement failed")
+		return fmt.Errorf("save settlement: %w", err)
+	}
+	return h.runSettlement(saga)
+}
+
+// runSettlement thực hiện các bước còn lại của saga, lưu lại sau mỗi bước
+func (h *CardHandler) runSettlement(saga model.Settlement) error {
+	h.chargeMu.Lock()
+	if h.settling[saga.TxID] {
+		h.chargeMu.Unlock()
+		return nil
+	}
+	h.settling[saga.TxID] = true
+	h.chargeMu.Unlock()
+	defer func() {
+		h.chargeMu.Lock()
+		delete(h.settling, saga.TxID)
+		h.chargeMu.Unlock()
+	}()
+
+	var tokenId [32]byte
+	copy(tokenId[:], e_common.FromHex(saga.TokenID))
+	merchant := common.HexToAddress(saga.Merchant)
+	amount, ok := new(big.Int).SetString(saga.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid settlement amount %q", saga.Amount)
+	}
+
+	for !saga.Done() {
+		var err error
+		next := saga.Step
+		switch saga.Step {
+		case model.SettlementGatewayConfirmed:
+			_, err = h.service.UpdateTxStatus(tokenId, saga.TxID, 2, uint64(time.Now().Unix()), "success")
+			next = model.SettlementStatusUpdated
+		case model.SettlementStatusUpdated:
+			next, err = h.mintUTXO(&saga, amount, merchant)
+		case model.SettlementUTXOMinted:
+			next, err = h.verifyPool(&saga, amount, merchant)
+		default:
+			err = fmt.Errorf("unknown settlement step %q", saga.Step)
+		}
+		saga.Attempts++
+		saga.UpdatedAt = time.Now().Unix()
+		if err != nil {
+			saga.LastError = err.Error()
+			if saga.Step == model.SettlementStatusUpdated && errors.Is(err, services.ErrTxUnconfirmed) {
+				// Không biết transaction đã vào block hay chưa, gửi lại có thể mint hai lần
+				saga.NeedsReview = true
+			}
+			if serr := h.DB.Settlements().Save(saga); serr != nil {
+				logger.Error("fail in save settlement:", serr)
+			}
+			return fmt.Errorf("settlement %s at %s: %w", saga.TxID, saga.Step, err)
+		}
+		saga.Step = next
+		saga.LastError = ""
+		if err := h.DB.Settlements().Save(saga); err != nil {
+			return fmt.Errorf("save settlement: %w", err)
+		}
+	}
+	h.transitionCharge(saga.TxID, model.ChargeSuccess, "utxo minted")
+	logger.Info("✅ Settlement hoàn tất:", saga.TxID)
+	return nil
+}
+
+// mintUTXO gửi MintUTXO cho saga. MintUTXO của contract không chặn trùng và ghi đè pool của txID,
+// nên trước mỗi lần gửi lại phải chắc chắn pool chưa có trên chain.
+func (h *CardHandler) mintUTXO(saga *model.Settlement, amount *big.Int, merchant common.Address) (string, error) {
+	if saga.Mints > 0 {
+		pool, err := h.query.GetPoolInfo(saga.TxID)
+		if err != nil {
+			return saga.Step, fmt.Errorf("check pool before re-mint: %w", err)
+		}
+		if pool.OwnerPool != (common.Address{}) {
+			logger.Info("pool đã có trên chain, bỏ qua MintUTXO:", saga.TxID)
+			return model.SettlementUTXOMinted, nil
+		}
+	}
+	saga.Mints++
+	kq, err := h.service.MintUTXO(amount, merchant, saga.TxID)
+	if err != nil {
+		return saga.Step, err
+	}
+	logger.Info("MintUTXO tx:", kq.TxHash.Hex(), "gas:", kq.GasUsed)
+	if pool, ok := kq.Output["newPool"].(common.Address); ok {
+		saga.Pool = pool.Hex()
+	}
+	return model.SettlementUTXOMinted, nil
+}
+
+// verifyPool đối chiếu getPoolInfo với merchant và số tiền đã mint. Bước này chỉ tới sau khi
+// MintUTXO có receipt thành công, nên pool rỗng là eth_call đọc trạng thái cũ: giữ nguyên bước để
+// lần retry sau đọc lại, quá settlementPoolChecks lần thì dừng chờ xử lý tay, không bao giờ mint lại.
+func (h *CardHandler) verifyPool(saga *model.Settlement, amount *big.Int, merchant common.Address) (string, error) {
+	pool, err := h.query.GetPoolInfo(saga.TxID)
+	if err != nil {
+		return saga.Step, err
+	}
+	if pool.OwnerPool == (common.Address{}) {
+		saga.PoolChecks++
+		if saga.PoolChecks >= settlementPoolChecks {
+			saga.NeedsReview = true
+		}
+		return saga.Step, fmt.Errorf("pool not found after MintUTXO (check %d)", saga.PoolChecks)
+	}
+	if pool.OwnerPool != merchant || pool.ParentValue == nil || pool.ParentValue.Cmp(amount) != 0 {
+		saga.NeedsReview = true
+		return saga.Step, fmt.Errorf("pool mismatch: owner %s value %v", pool.OwnerPool.Hex(), pool.ParentValue)
+	}
+	return model.SettlementPoolVerified, nil
+}
+
+// ResumeSettlements định kỳ retry các saga chưa hoàn tất, kể cả saga còn dở từ lần chạy trước.
+// Chỉ cần chạy trên deployment chính, mỗi saga được chuyển cho deployment sở hữu charge.
+func (h *CardHandler) ResumeSettlements() {
+	interval := h.config.SettlementRetryInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	for {
+		pending, err := h.DB.Settlements().ListPending()
+		if err != nil {
+			logger.Error("fail in list pending settlements:", err)
+		}
+		for _, saga := range pending {
+			if err := h.forTx(saga.TxID).runSettlement(saga); err != nil {
+				logger.Error("Error when resume settlement:", err)
+			}
+		}
+		time.Sleep(interval)
+	}
+}
+
+// failCharge ghi nhận giao dịch bị acquirer từ chối
+func (h *CardHandler) failCharge(tokenId [32]byte, cardHash [32]byte, txID string, reason string) {
+	if !h.claimCharge(txID, model.ChargeFailed, reason) {
+		return
+	}
+	h.recordDecline(tokenId, cardHash, txID, reason)
+	_, err := h.service.UpdateTxStatus(tokenId, txID, 0, uint64(time.Now().Unix()), reason)
+	if err != nil {
+		logger.Error("Error when UpdateTxStatus:", err)
+	}
+}
@@ -0,0 +1,57 @@
+package network
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	e_common "github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+// HandleAcquirerWebhook áp dụng callback trạng thái từ acquirer vào charge đã lưu,
+// dùng cùng luồng hoàn tất với monitorTransaction và dừng monitor đang chạy.
+func (h *CardHandler) HandleAcquirerWebhook(update model.AcquirerWebhook) error {
+	charge, err := h.DB.Charges().Get(update.TxID)
+	if err != nil {
+		return err
+	}
+	if owner := h.owner(charge); owner != h {
+		return owner.HandleAcquirerWebhook(update)
+	}
+	if update.MID != "" && charge.MID != "" && update.MID != charge.MID {
+		return fmt.Errorf("m_id mismatch for %s", update.TxID)
+	}
+	var tokenId, cardHash [32]byte
+	copy(tokenId[:], e_common.FromHex(charge.TokenID))
+	copy(cardHash[:], e_common.FromHex(charge.CardHash))
+
+	status := model.AcquirerStatus(update.Status, update.Message)
+	logger.Info("📬 Webhook acquirer:", update.TxID, update.Status)
+	switch status {
+	case model.AcquirerApproved:
+		h.cancelMonitor(update.TxID)
+		amount, ok := new(big.Int).SetString(charge.Amount, 10)
+		if !ok {
+			return fmt.Errorf("invalid charge amount %q", charge.Amount)
+		}
+		h.resetDeclines(tokenId, cardHash)
+		return h.settleCharge(tokenId, update.TxID, amount, common.HexToAddress(charge.Merchant))
+	case model.AcquirerDeclined:
+		h.cancelMonitor(update.TxID)
+		reason := update.Message
+		if reason == "" {
+			reason = update.Status
+		}
+		h.failCharge(tokenId, cardHash, update.TxID, reason)
+	case model.AcquirerPending:
+	default:
+		// Không đoán kết quả từ status lạ, để charge chờ kiểm tra tay; monitor vẫn chạy nếu còn
+		logger.Warn("status webhook không xác định, chuyển charge sang review:", update.TxID, update.Status)
+		if !charge.Final() {
+			h.transitionCharge(update.TxID, model.ChargeReview, fmt.Sprintf("unknown acquirer status %q", update.Status))
+		}
+	}
+	return nil
+}
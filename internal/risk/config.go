@@ -0,0 +1,23 @@
+package risk
+
+import "github.com/meta-node-blockchain/cardvisa/internal/config"
+
+// FromConfig dựng Engine với các rule mặc định theo cấu hình; trả về nil nếu tắt.
+func FromConfig(cfg config.RiskConfig) *Engine {
+	if !cfg.Enabled {
+		return nil
+	}
+	return NewEngine(
+		AmountThreshold{
+			ReviewAbove:  cfg.ReviewAmountAbove,
+			DeclineAbove: cfg.DeclineAmountAbove,
+		},
+		NewTokenCooldown{Cooldown: cfg.NewTokenCooldown},
+		MerchantCategory{
+			Blocked: cfg.BlockedCategories,
+			Review:  cfg.ReviewCategories,
+		},
+		RegionMismatch{},
+		NewCardVelocity(cfg.CardVelocityLimit, cfg.CardVelocityWindow),
+	)
+}
@@ -0,0 +1,74 @@
+package risk
+
+import (
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+type Decision int
+
+const (
+	Approve Decision = iota
+	Review
+	Decline
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Review:
+		return "review"
+	case Decline:
+		return "decline"
+	}
+	return "approve"
+}
+
+// Charge là dữ liệu của một ChargeRequest mà các rule dùng để chấm điểm.
+type Charge struct {
+	TokenID  [32]byte
+	CardHash [32]byte
+	Token    model.TokenInfo
+	Merchant model.Merchant
+	Amount   model.Money
+	At       time.Time
+}
+
+type Result struct {
+	Decision Decision
+	Rule     string
+	Reason   string
+}
+
+type Rule interface {
+	Name() string
+	Evaluate(c Charge) Result
+}
+
+// Engine chạy lần lượt các rule; Decline được ưu tiên hơn Review.
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+func (e *Engine) Evaluate(c Charge) Result {
+	final := Result{Decision: Approve}
+	if e == nil {
+		return final
+	}
+	for _, rule := range e.rules {
+		res := rule.Evaluate(c)
+		if res.Decision <= final.Decision {
+			continue
+		}
+		res.Rule = rule.Name()
+		final = res
+		if final.Decision == Decline {
+			break
+		}
+	}
+	return final
+}
@@ -0,0 +1,147 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AmountThreshold chuyển sang review hoặc từ chối khi số tiền (minor unit) vượt ngưỡng; 0 là bỏ qua.
+type AmountThreshold struct {
+	ReviewAbove  int64
+	DeclineAbove int64
+}
+
+func (r AmountThreshold) Name() string { return "amount_threshold" }
+
+func (r AmountThreshold) Evaluate(c Charge) Result {
+	if r.DeclineAbove > 0 && c.Amount.Amount > r.DeclineAbove {
+		return Result{Decision: Decline, Reason: fmt.Sprintf("amount %s exceeds limit", c.Amount)}
+	}
+	if r.ReviewAbove > 0 && c.Amount.Amount > r.ReviewAbove {
+		return Result{Decision: Review, Reason: fmt.Sprintf("amount %s requires review", c.Amount)}
+	}
+	return Result{Decision: Approve}
+}
+
+// NewTokenCooldown từ chối token vừa được phát hành trong khoảng Cooldown.
+type NewTokenCooldown struct {
+	Cooldown time.Duration
+}
+
+func (r NewTokenCooldown) Name() string { return "new_token_cooldown" }
+
+func (r NewTokenCooldown) Evaluate(c Charge) Result {
+	if r.Cooldown <= 0 || c.Token.IssuedAt == 0 {
+		return Result{Decision: Approve}
+	}
+	if c.At.Sub(time.Unix(c.Token.IssuedAt, 0)) < r.Cooldown {
+		return Result{Decision: Decline, Reason: "token is in cooldown period"}
+	}
+	return Result{Decision: Approve}
+}
+
+// MerchantCategory chặn hoặc review theo category (MCC) của merchant.
+type MerchantCategory struct {
+	Blocked []string
+	Review  []string
+}
+
+func (r MerchantCategory) Name() string { return "merchant_category" }
+
+func (r MerchantCategory) Evaluate(c Charge) Result {
+	if containsFold(r.Blocked, c.Merchant.Category) {
+		return Result{Decision: Decline, Reason: "merchant category not allowed"}
+	}
+	if containsFold(r.Review, c.Merchant.Category) {
+		return Result{Decision: Review, Reason: "merchant category requires review"}
+	}
+	return Result{Decision: Approve}
+}
+
+// RegionMismatch so sánh region của token với danh sách region merchant cho phép.
+type RegionMismatch struct{}
+
+func (r RegionMismatch) Name() string { return "region_mismatch" }
+
+func (r RegionMismatch) Evaluate(c Charge) Result {
+	if len(c.Merchant.AllowedRegions) == 0 {
+		return Result{Decision: Approve}
+	}
+	if c.Token.Region == "" {
+		return Result{Decision: Review, Reason: "token region unknown"}
+	}
+	if !containsFold(c.Merchant.AllowedRegions, c.Token.Region) {
+		return Result{Decision: Decline, Reason: "token region not allowed by merchant"}
+	}
+	return Result{Decision: Approve}
+}
+
+// CardVelocity giới hạn số charge của cùng một thẻ (cardHash) trên mọi token trong một cửa sổ thời gian.
+type CardVelocity struct {
+	Limit  int
+	Window time.Duration
+
+	mu   sync.Mutex
+	seen map[[32]byte][]time.Time
+	// lần cuối dọn các thẻ không còn charge nào trong Window
+	swept time.Time
+}
+
+func NewCardVelocity(limit int, window time.Duration) *CardVelocity {
+	return &CardVelocity{
+		Limit:  limit,
+		Window: window,
+		seen:   make(map[[32]byte][]time.Time),
+	}
+}
+
+func (r *CardVelocity) Name() string { return "card_velocity" }
+
+func (r *CardVelocity) Evaluate(c Charge) Result {
+	if r.Limit <= 0 || r.Window <= 0 {
+		return Result{Decision: Approve}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sweep(c.At)
+	recent := r.seen[c.CardHash][:0]
+	for _, t := range r.seen[c.CardHash] {
+		if c.At.Sub(t) < r.Window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= r.Limit {
+		r.seen[c.CardHash] = recent
+		return Result{Decision: Decline, Reason: "card velocity limit exceeded"}
+	}
+	r.seen[c.CardHash] = append(recent, c.At)
+	return Result{Decision: Approve}
+}
+
+// sweep xoá thẻ có charge mới nhất đã ra khỏi Window, tối đa một lần mỗi Window để map không
+// tăng mãi theo số thẻ từng charge
+func (r *CardVelocity) sweep(now time.Time) {
+	if now.Sub(r.swept) < r.Window {
+		return
+	}
+	r.swept = now
+	for cardHash, times := range r.seen {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= r.Window {
+			delete(r.seen, cardHash)
+		}
+	}
+}
+
+func containsFold(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.EqualFold(item, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package risk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+var now = time.Unix(1_700_000_000, 0)
+
+func vnd(amount int64) model.Money {
+	return model.Money{Amount: amount, Currency: "VND"}
+}
+
+func TestRules(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		rule   Rule
+		charge Charge
+		want   Decision
+	}{
+		{"amount under limits", AmountThreshold{ReviewAbove: 100, DeclineAbove: 1000}, Charge{Amount: vnd(100)}, Approve},
+		{"amount review", AmountThreshold{ReviewAbove: 100, DeclineAbove: 1000}, Charge{Amount: vnd(101)}, Review},
+		{"amount decline", AmountThreshold{ReviewAbove: 100, DeclineAbove: 1000}, Charge{Amount: vnd(1001)}, Decline},
+		{"amount disabled", AmountThreshold{}, Charge{Amount: vnd(1 << 40)}, Approve},
+
+		{"cooldown active", NewTokenCooldown{Cooldown: time.Hour},
+			Charge{Token: model.TokenInfo{IssuedAt: now.Add(-time.Minute).Unix()}, At: now}, Decline},
+		{"cooldown passed", NewTokenCooldown{Cooldown: time.Hour},
+			Charge{Token: model.TokenInfo{IssuedAt: now.Add(-2 * time.Hour).Unix()}, At: now}, Approve},
+		{"cooldown unknown issue time", NewTokenCooldown{Cooldown: time.Hour}, Charge{At: now}, Approve},
+
+		{"category blocked", MerchantCategory{Blocked: []string{"7995"}, Review: []string{"5933"}},
+			Charge{Merchant: model.Merchant{Category: "7995"}}, Decline},
+		{"category review", MerchantCategory{Blocked: []string{"7995"}, Review: []string{"5933"}},
+			Charge{Merchant: model.Merchant{Category: "5933"}}, Review},
+		{"category empty", MerchantCategory{Blocked: []string{"7995"}}, Charge{}, Approve},
+
+		{"region unrestricted", RegionMismatch{}, Charge{Token: model.TokenInfo{Region: "US"}}, Approve},
+		{"region allowed", RegionMismatch{},
+			Charge{Token: model.TokenInfo{Region: "vn"}, Merchant: model.Merchant{AllowedRegions: []string{"VN"}}}, Approve},
+		{"region mismatch", RegionMismatch{},
+			Charge{Token: model.TokenInfo{Region: "US"}, Merchant: model.Merchant{AllowedRegions: []string{"VN"}}}, Decline},
+		{"region unknown", RegionMismatch{},
+			Charge{Merchant: model.Merchant{AllowedRegions: []string{"VN"}}}, Review},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.rule.Evaluate(tc.charge); got.Decision != tc.want {
+				t.Fatalf("Evaluate = %v (%s), want %v", got.Decision, got.Reason, tc.want)
+			}
+		})
+	}
+}
+
+type velocityStep struct {
+	card [32]byte
+	at   time.Duration
+	want Decision
+}
+
+func TestCardVelocity(t *testing.T) {
+	card := [32]byte{1}
+	other := [32]byte{2}
+	for _, tc := range []struct {
+		name  string
+		steps []velocityStep
+	}{
+		{"limit per card across tokens", []velocityStep{
+			{card, 0, Approve},
+			{card, time.Second, Approve},
+			{card, 2 * time.Second, Decline},
+			{other, 3 * time.Second, Approve},
+		}},
+		{"window slides", []velocityStep{
+			{card, 0, Approve},
+			{card, time.Second, Approve},
+			{card, time.Minute, Approve},
+			{card, time.Minute + time.Second, Approve},
+			{card, time.Minute + 2*time.Second, Decline},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := NewCardVelocity(2, time.Minute)
+			for i, step := range tc.steps {
+				got := rule.Evaluate(Charge{CardHash: step.card, At: now.Add(step.at)})
+				if got.Decision != step.want {
+					t.Fatalf("step %d: Evaluate = %v, want %v", i, got.Decision, step.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCardVelocityPrunesIdleCards(t *testing.T) {
+	rule := NewCardVelocity(5, time.Minute)
+	for i := 0; i < 100; i++ {
+		rule.Evaluate(Charge{CardHash: [32]byte{byte(i)}, At: now})
+	}
+	if len(rule.seen) != 100 {
+		t.Fatalf("seen %d cards, want 100", len(rule.seen))
+	}
+	// Sau một Window, charge tiếp theo dọn các thẻ không còn charge nào trong cửa sổ
+	rule.Evaluate(Charge{CardHash: [32]byte{0xff}, At: now.Add(2 * time.Minute)})
+	if len(rule.seen) != 1 {
+		t.Fatalf("seen %d cards after window, want 1", len(rule.seen))
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	review := AmountThreshold{ReviewAbove: 10}
+	decline := MerchantCategory{Blocked: []string{"7995"}}
+	blocked := Charge{Amount: vnd(100), Merchant: model.Merchant{Category: "7995"}}
+	for _, tc := range []struct {
+		name     string
+		engine   *Engine
+		charge   Charge
+		want     Decision
+		wantRule string
+	}{
+		{"nil engine approves", nil, blocked, Approve, ""},
+		{"no rules approves", NewEngine(), blocked, Approve, ""},
+		{"review only", NewEngine(review), Charge{Amount: vnd(100)}, Review, "amount_threshold"},
+		{"decline wins over earlier review", NewEngine(review, decline), blocked, Decline, "merchant_category"},
+		{"decline stops later rules", NewEngine(decline, review), blocked, Decline, "merchant_category"},
+		{"all approve", NewEngine(review, decline), Charge{Amount: vnd(1)}, Approve, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.engine.Evaluate(tc.charge)
+			if got.Decision != tc.want || got.Rule != tc.wantRule {
+				t.Fatalf("Evaluate = %v/%q, want %v/%q", got.Decision, got.Rule, tc.want, tc.wantRule)
+			}
+		})
+	}
+}
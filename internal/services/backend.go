@@ -0,0 +1,59 @@
+package services
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/meta-node/cmd/client"
+	"github.com/meta-node-blockchain/meta-node/pkg/transaction"
+	"github.com/meta-node-blockchain/meta-node/types"
+)
+
+// ChainBackend ký, gửi một transaction tới chain và chờ receipt. TxManager gọi
+// SendTransaction lần lượt nên backend không cần tự tuần tự hoá nonce.
+type ChainBackend interface {
+	// Sender là địa chỉ ký transaction
+	Sender() common.Address
+	// SendTransaction gửi req.Data (ABI input thô) tới req.To và chờ receipt
+	SendTransaction(req TxRequest) (types.Receipt, error)
+	Close()
+}
+
+// metaNodeBackend gửi transaction qua client của meta-node bằng device key của sender
+type metaNodeBackend struct {
+	chainClient *client.Client
+	from        common.Address
+}
+
+// NewMetaNodeBackend dùng chainClient của meta-node, ký bằng key của from
+func NewMetaNodeBackend(chainClient *client.Client, from common.Address) ChainBackend {
+	return &metaNodeBackend{
+		chainClient: chainClient,
+		from:        from,
+	}
+}
+
+func (b *metaNodeBackend) Sender() common.Address {
+	return b.from
+}
+
+func (b *metaNodeBackend) SendTransaction(req TxRequest) (types.Receipt, error) {
+	bData, err := transaction.NewCallData(req.Data).Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return b.chainClient.SendTransactionWithDeviceKey(
+		b.from,
+		req.To,
+		big.NewInt(0),
+		bData,
+		[]common.Address{},
+		req.MaxGas,
+		req.MaxGasPrice,
+		req.TimeUse,
+	)
+}
+
+func (b *metaNodeBackend) Close() {
+	b.chainClient.Close()
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	ethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	pb "github.com/meta-node-blockchain/meta-node/pkg/proto"
+	"github.com/meta-node-blockchain/meta-node/types"
+)
+
+const (
+	receiptPollInterval = time.Second
+	// Sau thời gian này ethBackend thôi chờ receipt; TxManager thường đã trả ErrTxUnconfirmed trước đó
+	receiptMaxWait = 10 * time.Minute
+)
+
+// EthClient là phần JSON-RPC ethBackend cần; *ethclient.Client và client của
+// ethclient/simulated đều thoả interface này
+type EthClient interface {
+	ethereum.ChainIDReader
+	ethereum.ContractCaller
+	ethereum.GasPricer
+	ethereum.PendingStateReader
+	ethereum.TransactionReader
+	ethereum.TransactionSender
+}
+
+// ethBackend ký transaction bằng private key ECDSA và gửi qua Ethereum JSON-RPC chuẩn
+type ethBackend struct {
+	client  EthClient
+	key     *ecdsa.PrivateKey
+	from    common.Address
+	chainID *big.Int
+	closer  func()
+}
+
+// NewEthBackend dial rpcURL và ký bằng privateKeyHex (có hoặc không có tiền tố 0x)
+func NewEthBackend(rpcURL string, privateKeyHex string) (ChainBackend, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(privateKeyHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EthPrivateKey: %w", err)
+	}
+	client, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := NewEthBackendWithClient(client, key)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	backend.(*ethBackend).closer = client.Close
+	return backend, nil
+}
+
+// NewEthBackendWithClient dùng client có sẵn (ví dụ simulated backend); Close không đóng client
+func NewEthBackendWithClient(client EthClient, key *ecdsa.PrivateKey) (ChainBackend, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &ethBackend{
+		client:  client,
+		key:     key,
+		from:    crypto.PubkeyToAddress(key.PublicKey),
+		chainID: chainID,
+	}, nil
+}
+
+func (b *ethBackend) Sender() common.Address {
+	return b.from
+}
+
+// SendTransaction gửi legacy transaction và chờ receipt. Receipt Ethereum không chứa dữ liệu
+// trả về nên Return được lấy bằng eth_call lại tại block cha (dữ liệu revert khi thất bại).
+func (b *ethBackend) SendTransaction(req TxRequest) (types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), receiptMaxWait)
+	defer cancel()
+
+	nonce, err := b.client.PendingNonceAt(ctx, b.from)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := ethtypes.SignNewTx(b.key, ethtypes.LatestSignerForChainID(b.chainID), &ethtypes.LegacyTx{
+		Nonce:    nonce,
+		To:       &req.To,
+		Value:    big.NewInt(0),
+		Gas:      req.MaxGas,
+		GasPrice: b.gasPrice(ctx, req.MaxGasPrice),
+		Data:     req.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := b.client.SendTransaction(ctx, tx); err != nil {
+		return nil, err
+	}
+	if req.submitted != nil {
+		req.submitted(tx.Hash())
+	}
+
+	receipt, err := b.waitReceipt(ctx, tx.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return b.toReceipt(ctx, req, receipt), nil
+}
+
+// Receipt tra receipt của transaction đã gửi trước đó, nil khi chưa vào block
+func (b *ethBackend) Receipt(req TxRequest, hash common.Hash) (types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	receipt, err := b.client.TransactionReceipt(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b.toReceipt(ctx, req, receipt), nil
+}
+
+func (b *ethBackend) toReceipt(ctx context.Context, req TxRequest, receipt *ethtypes.Receipt) types.Receipt {
+	status := pb.RECEIPT_STATUS_RETURNED
+	if receipt.Status != ethtypes.ReceiptStatusSuccessful {
+		status = pb.RECEIPT_STATUS_THREW
+	}
+	return &ethReceipt{
+		hash:    receipt.TxHash,
+		gasUsed: receipt.GasUsed,
+		ret:     b.replay(ctx, req, receipt.BlockNumber),
+		status:  status,
+	}
+}
+
+func (b *ethBackend) Close() {
+	if b.closer != nil {
+		b.closer()
+	}
+}
+
+// gasPrice lấy giá gợi ý của node, không vượt maxGasPrice
+func (b *ethBackend) gasPrice(ctx context.Context, maxGasPrice uint64) *big.Int {
+	limit := new(big.Int).SetUint64(maxGasPrice)
+	suggested, err := b.client.SuggestGasPrice(ctx)
+	if err != nil {
+		return limit
+	}
+	if maxGasPrice > 0 && suggested.Cmp(limit) > 0 {
+		return limit
+	}
+	return suggested
+}
+
+func (b *ethBackend) waitReceipt(ctx context.Context, hash common.Hash) (*ethtypes.Receipt, error) {
+	ticker := time.NewTicker(receiptPollInterval)
+	defer ticker.Stop()
+	for {
+		receipt, err := b.client.TransactionReceipt(ctx, hash)
+		if err == nil {
+			return receipt, nil
+		}
+		// Node mới khởi động trả lỗi "transaction indexing is in progress" thay vì NotFound
+		if !errors.Is(err, ethereum.NotFound) && !strings.Contains(err.Error(), "indexing is in progress") {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("receipt %s: %w", hash.Hex(), ErrTxUnconfirmed)
+		case <-ticker.C:
+		}
+	}
+}
+
+// replay chạy lại lời gọi tại block cha của block chứa transaction để lấy dữ liệu trả về
+func (b *ethBackend) replay(ctx context.Context, req TxRequest, blockNumber *big.Int) []byte {
+	var parent *big.Int
+	if blockNumber != nil && blockNumber.Sign() > 0 {
+		parent = new(big.Int).Sub(blockNumber, big.NewInt(1))
+	}
+	out, err := b.client.CallContract(ctx, ethereum.CallMsg{
+		From: b.from,
+		To:   &req.To,
+		Gas:  req.MaxGas,
+		Data: req.Data,
+	}, parent)
+	if err != nil {
+		return revertData(err)
+	}
+	return out
+}
+
+// revertData đọc dữ liệu revert từ lỗi JSON-RPC (rpc.DataError)
+func revertData(err error) []byte {
+	var dataErr interface{ ErrorData() interface{} }
+	if !errors.As(err, &dataErr) {
+		return nil
+	}
+	s, ok := dataErr.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+	data, decodeErr := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if decodeErr != nil {
+		return nil
+	}
+	return data
+}
+
+// ethReceipt là receipt Ethereum dưới dạng types.Receipt của meta-node
+type ethReceipt struct {
+	hash    common.Hash
+	gasUsed uint64
+	ret     []byte
+	status  pb.RECEIPT_STATUS
+}
+
+func (r *ethReceipt) TransactionHash() common.Hash { return r.hash }
+func (r *ethReceipt) GasUsed() uint64              { return r.gasUsed }
+func (r *ethReceipt) Return() []byte               { return r.ret }
+func (r *ethReceipt) Status() pb.RECEIPT_STATUS    { return r.status }
@@ -0,0 +1,216 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// Outcome là kiểu phản hồi gateway trả cho một charge, theo các dạng SendToThirdParty nhận diện
+type Outcome string
+
+const (
+	// body chứa "success"
+	OutcomeSuccess Outcome = "success"
+	// body chứa "being processed"
+	OutcomePending Outcome = "pending"
+	// status failed nhưng message "Transaction failed, pending", handler coi là đang xử lý
+	OutcomePendingFailed Outcome = "pending-failed"
+	// status failed với lý do từ chối
+	OutcomeFailed Outcome = "failed"
+	// yêu cầu chủ thẻ nhập OTP, hoàn tất qua ChallengeURL
+	OutcomeOTP Outcome = "otp"
+	// yêu cầu chuyển hướng 3-D Secure
+	OutcomeRedirect Outcome = "redirect"
+)
+
+// DefaultOTP là OTP ChallengeURL chấp nhận nếu không đặt Gateway.OTP
+const DefaultOTP = "123456"
+
+// ChargeRequest là payload SendToThirdParty gửi tới gateway
+type ChargeRequest struct {
+	MID        string `json:"m_id"`
+	TerminalID string `json:"terminal_id,omitempty"`
+	TxID       string `json:"tx_id"`
+	CardNumber string `json:"card_number"`
+	ExpDate    string `json:"exp_date"`
+	Amount     int64  `json:"amount"`
+	Currency   string `json:"currency"`
+	WalletTo   string `json:"wallet_to"`
+	FeePayer   int    `json:"fee_payer"`
+	CVV        string `json:"cvv"`
+}
+
+// ChallengeRequest là payload SubmitChallengeResult gửi tới gateway
+type ChallengeRequest struct {
+	TxID string `json:"tx_id"`
+	MID  string `json:"m_id"`
+	OTP  string `json:"otp"`
+}
+
+// Gateway là acquirer giả chạy trên httptest. Charge gửi tới ChargeURL nhận lần lượt các
+// Outcome đã Script, hết script thì dùng Default; OTP gửi tới ChallengeURL thành công khi khớp OTP.
+type Gateway struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	defaultResult Outcome
+	script        []Outcome
+	body          string
+	declineReason string
+	otp           string
+	charges       []ChargeRequest
+	challenges    []ChallengeRequest
+}
+
+// NewGateway khởi động gateway với kết quả mặc định cho mọi charge
+func NewGateway(defaultResult Outcome) *Gateway {
+	g := &Gateway{
+		defaultResult: defaultResult,
+		declineReason: "Card declined",
+		otp:           DefaultOTP,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/charge", g.handleCharge)
+	mux.HandleFunc("/challenge", g.handleChallenge)
+	g.server = httptest.NewServer(mux)
+	return g
+}
+
+func (g *Gateway) ChargeURL() string {
+	return g.server.URL + "/charge"
+}
+
+func (g *Gateway) ChallengeURL() string {
+	return g.server.URL + "/challenge"
+}
+
+func (g *Gateway) Close() {
+	g.server.Close()
+}
+
+// Script đặt kết quả cho các charge kế tiếp theo thứ tự
+func (g *Gateway) Script(outcomes ...Outcome) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.script = append(g.script, outcomes...)
+}
+
+// Respond trả nguyên body cho mọi charge, bỏ qua Script và Default; "" để tắt
+func (g *Gateway) Respond(body string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.body = body
+}
+
+// Decline đặt message trong phản hồi OutcomeFailed
+func (g *Gateway) Decline(reason string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.declineReason = reason
+}
+
+// SetOTP đặt OTP ChallengeURL chấp nhận
+func (g *Gateway) SetOTP(otp string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.otp = otp
+}
+
+// Charges trả về các charge đã nhận theo thứ tự
+func (g *Gateway) Charges() []ChargeRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ChargeRequest{}, g.charges...)
+}
+
+// Challenges trả về các lần gửi OTP đã nhận theo thứ tự
+func (g *Gateway) Challenges() []ChallengeRequest {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]ChallengeRequest{}, g.challenges...)
+}
+
+func (g *Gateway) handleCharge(w http.ResponseWriter, r *http.Request) {
+	var req ChargeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	g.mu.Lock()
+	g.charges = append(g.charges, req)
+	body := g.body
+	if body == "" {
+		outcome := g.defaultResult
+		if len(g.script) > 0 {
+			outcome = g.script[0]
+			g.script = g.script[1:]
+		}
+		body = g.chargeBody(outcome, req.TxID)
+	}
+	g.mu.Unlock()
+	writeJSON(w, body)
+}
+
+func (g *Gateway) handleChallenge(w http.ResponseWriter, r *http.Request) {
+	var req ChallengeRequest
+	if !decode(w, r, &req) {
+		return
+	}
+	g.mu.Lock()
+	g.challenges = append(g.challenges, req)
+	ok := req.OTP == g.otp
+	g.mu.Unlock()
+	if ok {
+		writeJSON(w, statusBody("success", "Transaction success", req.TxID))
+		return
+	}
+	writeJSON(w, statusBody("failed", "Invalid OTP", req.TxID))
+}
+
+// chargeBody dựng body theo outcome; chỉ body success được chứa chữ "success"
+func (g *Gateway) chargeBody(outcome Outcome, txID string) string {
+	switch outcome {
+	case OutcomeSuccess:
+		return statusBody("success", "Transaction success", txID)
+	case OutcomePending:
+		return statusBody("being processed", "Transaction is being processed", txID)
+	case OutcomePendingFailed:
+		return statusBody("failed", "Transaction failed, pending", txID)
+	case OutcomeOTP:
+		return `{"otp_required":true,"challenge_type":"otp"}`
+	case OutcomeRedirect:
+		return fmt.Sprintf(`{"redirect_url":%q}`, g.server.URL+"/3ds/"+txID)
+	default:
+		return statusBody("failed", g.declineReason, txID)
+	}
+}
+
+func statusBody(status string, message string, txID string) string {
+	data, _ := json.Marshal(map[string]string{
+		"status":        status,
+		"message":       message,
+		"transactionID": txID,
+	})
+	return string(data)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, out interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, body)
+}
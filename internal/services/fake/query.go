@@ -0,0 +1,200 @@
+package fake
+
+import (
+	"encoding/hex"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+)
+
+// Query giữ trạng thái contract trong bộ nhớ. Giống view của Solidity, khoá chưa có
+// trả về giá trị rỗng chứ không báo lỗi; dùng Fail để giả lập RPC lỗi.
+type Query struct {
+	mu            sync.Mutex
+	err           error
+	txs           map[string]model.TxStatus
+	lastTxID      map[[32]byte]string
+	pools         map[string]model.PoolInfo
+	backendPubKey []byte
+	userTokens    map[common.Address][][32]byte
+	requestTokens map[[32]byte][32]byte
+	tokens        map[[32]byte]model.TokenState
+	merchantRules map[common.Address]model.MerchantRule
+	globalRule    model.GlobalRule
+	locked        bool
+}
+
+var _ services.ContractQuery = (*Query)(nil)
+
+func NewQuery() *Query {
+	return &Query{
+		txs:           make(map[string]model.TxStatus),
+		lastTxID:      make(map[[32]byte]string),
+		pools:         make(map[string]model.PoolInfo),
+		userTokens:    make(map[common.Address][][32]byte),
+		requestTokens: make(map[[32]byte][32]byte),
+		tokens:        make(map[[32]byte]model.TokenState),
+		merchantRules: make(map[common.Address]model.MerchantRule),
+	}
+}
+
+// Fail làm mọi lời gọi sau đó trả err; Fail(nil) để chạy lại bình thường
+func (q *Query) Fail(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.err = err
+}
+
+func (q *Query) SetTx(tokenId [32]byte, tx model.TxStatus) {
+	q.updateTx(tokenId, tx)
+}
+
+func (q *Query) SetPool(txID string, pool model.PoolInfo) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pools[txID] = pool
+}
+
+func (q *Query) SetBackendPubKey(pubKey []byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.backendPubKey = append([]byte{}, pubKey...)
+}
+
+// SetToken thêm token như submitToken đã chạy, không kiểm tra requestId hay cardHash
+func (q *Query) SetToken(user common.Address, tokenId [32]byte, region string) {
+	q.issueToken(user, tokenId, region, [32]byte{}, [32]byte{})
+}
+
+func (q *Query) SetMerchantRule(rule model.MerchantRule) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.merchantRules[common.HexToAddress(rule.Merchant)] = rule
+}
+
+func (q *Query) SetGlobalRule(rule model.GlobalRule) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.globalRule = rule
+}
+
+func (q *Query) SetLocked(locked bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.locked = locked
+}
+
+func (q *Query) issueToken(user common.Address, tokenId [32]byte, region string, requestId [32]byte, cardHash [32]byte) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.tokens[tokenId]; !ok {
+		q.userTokens[user] = append(q.userTokens[user], tokenId)
+	}
+	if requestId != ([32]byte{}) {
+		q.requestTokens[requestId] = tokenId
+	}
+	q.tokens[tokenId] = model.TokenState{
+		TokenID:  hex.EncodeToString(tokenId[:]),
+		Owner:    user.Hex(),
+		Region:   region,
+		IssuedAt: uint64(time.Now().Unix()),
+		Active:   true,
+		CardHash: hex.EncodeToString(cardHash[:]),
+	}
+}
+
+func (q *Query) updateTx(tokenId [32]byte, tx model.TxStatus) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.txs[tx.TxID] = tx
+	q.lastTxID[tokenId] = tx.TxID
+}
+
+func (q *Query) setTokenActive(tokenId [32]byte, active bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if token, ok := q.tokens[tokenId]; ok {
+		token.Active = active
+		q.tokens[tokenId] = token
+	}
+}
+
+func (q *Query) GetTx(txID string) (model.TxStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.txs[txID], q.err
+}
+
+func (q *Query) GetPoolInfo(txID string) (model.PoolInfo, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pool := q.pools[txID]
+	if pool.ParentValue != nil {
+		pool.ParentValue = new(big.Int).Set(pool.ParentValue)
+	}
+	return pool, q.err
+}
+
+func (q *Query) GetBackendPubKey() ([]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]byte{}, q.backendPubKey...), q.err
+}
+
+func (q *Query) GetUserTokens(user common.Address) ([][32]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([][32]byte{}, q.userTokens[user]...), q.err
+}
+
+func (q *Query) GetLastTxID(tokenId [32]byte) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.lastTxID[tokenId], q.err
+}
+
+func (q *Query) GetTokenIdByRequestId(requestId [32]byte) ([32]byte, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.requestTokens[requestId], q.err
+}
+
+// MerchantRule không trả AllowedRegions, giống public getter merchantRules của contract
+func (q *Query) MerchantRule(merchant common.Address) (model.MerchantRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	rule := q.merchantRules[merchant]
+	rule.Merchant = merchant.Hex()
+	rule.AllowedRegions = nil
+	return rule, q.err
+}
+
+func (q *Query) GlobalRule() (model.GlobalRule, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.globalRule, q.err
+}
+
+func (q *Query) Token(tokenId [32]byte) (model.TokenState, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	token, ok := q.tokens[tokenId]
+	if !ok {
+		token = model.TokenState{
+			TokenID:  hex.EncodeToString(tokenId[:]),
+			Owner:    common.Address{}.Hex(),
+			CardHash: hex.EncodeToString(make([]byte, 32)),
+		}
+	}
+	return token, q.err
+}
+
+func (q *Query) IsLocked() (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.locked, q.err
+}
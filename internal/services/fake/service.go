@@ -0,0 +1,259 @@
+// Package fake có bản giả của SendTransactionService, ContractQuery và gateway acquirer
+// để test CardHandler mà không cần chain hay acquirer thật.
+package fake
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/cardvisa/internal/services"
+	pb "github.com/meta-node-blockchain/meta-node/pkg/proto"
+	"github.com/meta-node-blockchain/meta-node/types"
+)
+
+// Call là một lời gọi tới Service, Method là tên method trong ABI của contract
+type Call struct {
+	Method string
+	Args   []interface{}
+	At     time.Time
+}
+
+// Service ghi lại mọi lời gọi và trả TxResult giả. Lỗi được lập trình trước theo method
+// bằng Fail/Revert; nếu có Query thì các lời gọi ghi trạng thái (UpdateTxStatus, MintUTXO,
+// SetTokenActive...) cập nhật luôn Query như contract thật.
+type Service struct {
+	query *Query
+
+	mu     sync.Mutex
+	calls  []Call
+	errs   map[string][]error
+	nonce  uint64
+	onCall func(call Call) error
+}
+
+var _ services.SendTransactionService = (*Service)(nil)
+
+// NewService tạo Service giả; query có thể nil
+func NewService(query *Query) *Service {
+	return &Service{
+		query: query,
+		errs:  make(map[string][]error),
+	}
+}
+
+// Fail làm các lời gọi kế tiếp tới method trả lần lượt các lỗi errs
+func (s *Service) Fail(method string, errs ...error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errs[method] = append(s.errs[method], errs...)
+}
+
+// Revert làm lời gọi kế tiếp tới method trả *services.TxError với lý do reason
+func (s *Service) Revert(method string, reason string) {
+	s.Fail(method, &services.TxError{
+		Method: method,
+		Status: pb.RECEIPT_STATUS_THREW,
+		Reason: reason,
+	})
+}
+
+// OnCall đặt hook chạy trước mỗi lời gọi, lỗi trả về thay cho kết quả (vd để chặn lời gọi chờ test)
+func (s *Service) OnCall(hook func(call Call) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCall = hook
+}
+
+// Calls trả về các lời gọi đã nhận theo thứ tự
+func (s *Service) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call{}, s.calls...)
+}
+
+// CallsTo trả về các lời gọi tới một method
+func (s *Service) CallsTo(method string) []Call {
+	var out []Call
+	for _, call := range s.Calls() {
+		if call.Method == method {
+			out = append(out, call)
+		}
+	}
+	return out
+}
+
+// Reset xoá lịch sử lời gọi và các lỗi đã lập trình
+func (s *Service) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+	s.errs = make(map[string][]error)
+}
+
+func (s *Service) record(method string, args ...interface{}) (*services.TxResult, error) {
+	call := Call{Method: method, Args: args, At: time.Now()}
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	hook := s.onCall
+	var err error
+	if queued := s.errs[method]; len(queued) > 0 {
+		err = queued[0]
+		s.errs[method] = queued[1:]
+	}
+	s.nonce++
+	nonce := s.nonce
+	s.mu.Unlock()
+
+	if err == nil && hook != nil {
+		err = hook(call)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &services.TxResult{
+		Method:  method,
+		TxHash:  txHash(nonce),
+		GasUsed: 21_000,
+	}, nil
+}
+
+func txHash(nonce uint64) common.Hash {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], nonce)
+	return crypto.Keccak256Hash([]byte("fake"), b[:])
+}
+
+func (s *Service) SubmitToken(user common.Address, tokenid [32]byte, region string, requestId [32]byte, cardHash [32]byte) (*services.TxResult, error) {
+	result, err := s.record("submitToken", user, tokenid, region, requestId, cardHash)
+	if err == nil && s.query != nil {
+		s.query.issueToken(user, tokenid, region, requestId, cardHash)
+	}
+	return result, err
+}
+
+func (s *Service) UpdateTxStatus(tokenid [32]byte, txID string, status uint8, atTime uint64, reason string) (*services.TxResult, error) {
+	result, err := s.record("UpdateTxStatus", tokenid, txID, status, atTime, reason)
+	if err == nil && s.query != nil {
+		s.query.updateTx(tokenid, model.TxStatus{TxID: txID, Status: status, AtTime: atTime, Reason: reason})
+	}
+	return result, err
+}
+
+// UpdateTxStatusBatch bỏ qua phần tử có TxID rỗng giống batchUpdateTxStatus của contract
+func (s *Service) UpdateTxStatusBatch(updates []services.TxStatusUpdate) (*services.TxResult, []bool, error) {
+	result, err := s.record("batchUpdateTxStatus", updates)
+	if err != nil {
+		return nil, nil, err
+	}
+	ok := make([]bool, len(updates))
+	for i, u := range updates {
+		if u.TxID == "" {
+			continue
+		}
+		ok[i] = true
+		if s.query != nil {
+			s.query.updateTx(u.TokenId, model.TxStatus{TxID: u.TxID, Status: u.Status, AtTime: u.AtTime, Reason: u.Reason})
+		}
+	}
+	return result, ok, nil
+}
+
+func (s *Service) MintUTXO(parentValue *big.Int, ownerPool common.Address, txID string) (*services.TxResult, error) {
+	result, err := s.record("MintUTXO", parentValue, ownerPool, txID)
+	if err == nil && s.query != nil {
+		s.query.SetPool(txID, model.PoolInfo{
+			OwnerPool:   ownerPool,
+			ParentHash:  result.TxHash,
+			Pool:        common.BytesToAddress(result.TxHash[:20]),
+			ParentValue: new(big.Int).Set(parentValue),
+		})
+	}
+	return result, err
+}
+
+func (s *Service) SetCardLocked(cardHash [32]byte, locked bool) (*services.TxResult, error) {
+	return s.record("setCardLocked", cardHash, locked)
+}
+
+func (s *Service) SetTokenActive(tokenid [32]byte, active bool) (*services.TxResult, error) {
+	result, err := s.record("setTokenActive", tokenid, active)
+	if err == nil && s.query != nil {
+		s.query.setTokenActive(tokenid, active)
+	}
+	return result, err
+}
+
+func (s *Service) SetMerchantRule(rule model.MerchantRule) (*services.TxResult, error) {
+	result, err := s.record("setMerchantRule", rule)
+	if err == nil && s.query != nil {
+		s.query.SetMerchantRule(rule)
+	}
+	return result, err
+}
+
+func (s *Service) SetGlobalRule(rule model.GlobalRule) (*services.TxResult, error) {
+	result, err := s.record("setGlobalRule", rule)
+	if err == nil && s.query != nil {
+		s.query.SetGlobalRule(rule)
+	}
+	return result, err
+}
+
+func (s *Service) SetLock(locked bool) (*services.TxResult, error) {
+	result, err := s.record("setLock", locked)
+	if err == nil && s.query != nil {
+		s.query.SetLocked(locked)
+	}
+	return result, err
+}
+
+func (s *Service) SetAdmin(admin common.Address, ok bool) (*services.TxResult, error) {
+	return s.record("setAdmin", admin, ok)
+}
+
+func (s *Service) SetProcessor(processor common.Address) (*services.TxResult, error) {
+	return s.record("setProcessor", processor)
+}
+
+func (s *Service) SetBackendPubKey(pubKey []byte) (*services.TxResult, error) {
+	result, err := s.record("setBackendPubKey", pubKey)
+	if err == nil && s.query != nil {
+		s.query.SetBackendPubKey(pubKey)
+	}
+	return result, err
+}
+
+func (s *Service) CleanUsage(beforeTimestamp uint64) (*services.TxResult, error) {
+	return s.record("cleanUsage", beforeTimestamp)
+}
+
+// SendTransaction ghi lời gọi với calldata thô và trả receipt RETURNED không có dữ liệu
+func (s *Service) SendTransaction(methodName string, input []byte, attempts int) (types.Receipt, error) {
+	result, err := s.record(methodName, input)
+	if err != nil {
+		return nil, err
+	}
+	return &Receipt{Hash: result.TxHash, Gas: result.GasUsed, Status_: pb.RECEIPT_STATUS_RETURNED}, nil
+}
+
+func (s *Service) QueueStats() services.TxQueueStats {
+	return services.TxQueueStats{Sender: "fake"}
+}
+
+// Receipt là types.Receipt dựng sẵn cho test
+type Receipt struct {
+	Hash    common.Hash
+	Gas     uint64
+	Data    []byte
+	Status_ pb.RECEIPT_STATUS
+}
+
+func (r *Receipt) TransactionHash() common.Hash { return r.Hash }
+func (r *Receipt) GasUsed() uint64              { return r.Gas }
+func (r *Receipt) Return() []byte               { return r.Data }
+func (r *Receipt) Status() pb.RECEIPT_STATUS    { return r.Status_ }
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// GasEstimator ước lượng gas của transaction trước khi gửi
+type GasEstimator interface {
+	EstimateGas(from common.Address, to common.Address, input []byte) (uint64, error)
+}
+
+type rpcGasEstimator struct {
+	rpc *ethclient.Client
+}
+
+// NewGasEstimator ước lượng gas bằng eth_estimateGas qua rpcURL
+func NewGasEstimator(rpcURL string) (GasEstimator, error) {
+	rpc, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	return &rpcGasEstimator{rpc: rpc}, nil
+}
+
+func (e *rpcGasEstimator) EstimateGas(from common.Address, to common.Address, input []byte) (uint64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	return e.rpc.EstimateGas(ctx, ethereum.CallMsg{
+		From: from,
+		To:   &to,
+		Data: input,
+	})
+}
@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+const queryTimeout = 15 * time.Second
+
+// ContractQuery đọc state của card contract qua các hàm view, không tạo transaction
+type ContractQuery interface {
+	GetTx(txID string) (model.TxStatus, error)
+	GetPoolInfo(txID string) (model.PoolInfo, error)
+	GetBackendPubKey() ([]byte, error)
+	GetUserTokens(user common.Address) ([][32]byte, error)
+	GetLastTxID(tokenId [32]byte) (string, error)
+	GetTokenIdByRequestId(requestId [32]byte) ([32]byte, error)
+	MerchantRule(merchant common.Address) (model.MerchantRule, error)
+	GlobalRule() (model.GlobalRule, error)
+	Token(tokenId [32]byte) (model.TokenState, error)
+	IsLocked() (bool, error)
+}
+
+// viewCaller chạy calldata đã pack và trả về dữ liệu ABI-encoded của hàm view
+type viewCaller func(methodName string, input []byte) ([]byte, error)
+
+type queryService struct {
+	cardAbi *abi.ABI
+	call    viewCaller
+}
+
+// NewEthCallQuery đọc contract bằng eth_call qua RpcURL
+func NewEthCallQuery(
+	rpcURL string,
+	cardAbi *abi.ABI,
+	cardAddress common.Address,
+	fromAddress common.Address,
+) (ContractQuery, error) {
+	rpc, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return nil, err
+	}
+	call := func(methodName string, input []byte) ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+		defer cancel()
+		return rpc.CallContract(ctx, ethereum.CallMsg{
+			From: fromAddress,
+			To:   &cardAddress,
+			Data: input,
+		}, nil)
+	}
+	return &queryService{cardAbi: cardAbi, call: call}, nil
+}
+
+// NewTxQuery đọc contract qua đường gửi transaction của meta-node, chỉ dùng khi chưa cấu hình RpcURL
+func NewTxQuery(transactor Transactor, cardAbi *abi.ABI) ContractQuery {
+	call := func(methodName string, input []byte) ([]byte, error) {
+		receipt, err := transactor.SendTransaction(methodName, input, 1)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := newTxResult(methodName, receipt, cardAbi); err != nil {
+			return nil, err
+		}
+		return receipt.Return(), nil
+	}
+	return &queryService{cardAbi: cardAbi, call: call}
+}
+
+func (q *queryService) callView(out interface{}, method string, args ...interface{}) error {
+	input, err := q.cardAbi.Pack(method, args...)
+	if err != nil {
+		return err
+	}
+	data, err := q.call(method, input)
+	if err != nil {
+		return fmt.Errorf("call %s: %w", method, err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("call %s: empty result", method)
+	}
+	values, err := q.cardAbi.Unpack(method, data)
+	if err != nil {
+		return fmt.Errorf("unpack %s: %w", method, err)
+	}
+	if len(values) == 1 {
+		return convertInto(out, values[0], method)
+	}
+	if err := q.cardAbi.Methods[method].Outputs.Copy(out, values); err != nil {
+		return fmt.Errorf("decode %s: %w", method, err)
+	}
+	return nil
+}
+
+// convertInto gán một giá trị đã unpack (kể cả tuple) vào out, abi.ConvertType panic khi kiểu không khớp
+func convertInto(out interface{}, value interface{}, method string) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decode %s: %v", method, r)
+		}
+	}()
+	abi.ConvertType(value, out)
+	return nil
+}
+
+func (q *queryService) GetTx(txID string) (model.TxStatus, error) {
+	var tx model.TxStatus
+	err := q.callView(&tx, "getTx", txID)
+	return tx, err
+}
+
+func (q *queryService) GetPoolInfo(txID string) (model.PoolInfo, error) {
+	var info model.PoolInfo
+	err := q.callView(&info, "getPoolInfo", txID)
+	return info, err
+}
+
+func (q *queryService) GetBackendPubKey() ([]byte, error) {
+	var pubKey []byte
+	err := q.callView(&pubKey, "getBackendPubKey")
+	return pubKey, err
+}
+
+func (q *queryService) GetUserTokens(user common.Address) ([][32]byte, error) {
+	var tokens [][32]byte
+	err := q.callView(&tokens, "getUserTokens", user)
+	return tokens, err
+}
+
+func (q *queryService) GetLastTxID(tokenId [32]byte) (string, error) {
+	var txID string
+	err := q.callView(&txID, "getLastTxID", tokenId)
+	return txID, err
+}
+
+func (q *queryService) GetTokenIdByRequestId(requestId [32]byte) ([32]byte, error) {
+	var tokenId [32]byte
+	err := q.callView(&tokenId, "getTokenIdByRequestId", requestId)
+	return tokenId, err
+}
+
+func (q *queryService) MerchantRule(merchant common.Address) (model.MerchantRule, error) {
+	var out struct {
+		MaxPerMinute *big.Int
+		MaxPerHour   *big.Int
+		MaxPerDay    *big.Int
+		MaxPerWeek   *big.Int
+	}
+	if err := q.callView(&out, "merchantRules", merchant); err != nil {
+		return model.MerchantRule{}, err
+	}
+	// public getter của Solidity không trả mảng allowedRegions
+	rule := model.MerchantRule{Merchant: merchant.Hex()}
+	err := uint64s(
+		[]*big.Int{out.MaxPerMinute, out.MaxPerHour, out.MaxPerDay, out.MaxPerWeek},
+		[]*uint64{&rule.MaxPerMinute, &rule.MaxPerHour, &rule.MaxPerDay, &rule.MaxPerWeek},
+	)
+	return rule, err
+}
+
+func (q *queryService) GlobalRule() (model.GlobalRule, error) {
+	var out struct {
+		MaxPerMinute *big.Int
+		MaxPerHour   *big.Int
+		MaxPerDay    *big.Int
+		MaxPerWeek   *big.Int
+		MaxTotal     *big.Int
+	}
+	if err := q.callView(&out, "smRule"); err != nil {
+		return model.GlobalRule{}, err
+	}
+	var rule model.GlobalRule
+	err := uint64s(
+		[]*big.Int{out.MaxPerMinute, out.MaxPerHour, out.MaxPerDay, out.MaxPerWeek, out.MaxTotal},
+		[]*uint64{&rule.MaxPerMinute, &rule.MaxPerHour, &rule.MaxPerDay, &rule.MaxPerWeek, &rule.MaxTotal},
+	)
+	return rule, err
+}
+
+func (q *queryService) Token(tokenId [32]byte) (model.TokenState, error) {
+	var out struct {
+		Owner      common.Address
+		Region     string
+		IssuedAt   *big.Int
+		IsActive   bool
+		TotalUsage *big.Int
+		CardHash   [32]byte
+	}
+	if err := q.callView(&out, "tokens", tokenId); err != nil {
+		return model.TokenState{}, err
+	}
+	token := model.TokenState{
+		TokenID:  hex.EncodeToString(tokenId[:]),
+		Owner:    out.Owner.Hex(),
+		Region:   out.Region,
+		Active:   out.IsActive,
+		CardHash: hex.EncodeToString(out.CardHash[:]),
+	}
+	err := uint64s(
+		[]*big.Int{out.IssuedAt, out.TotalUsage},
+		[]*uint64{&token.IssuedAt, &token.TotalUsage},
+	)
+	return token, err
+}
+
+func (q *queryService) IsLocked() (bool, error) {
+	var locked bool
+	err := q.callView(&locked, "isLocked")
+	return locked, err
+}
+
+func uint64s(values []*big.Int, out []*uint64) error {
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if !v.IsUint64() {
+			return errors.New("contract value overflows uint64: " + v.String())
+		}
+		*out[i] = v.Uint64()
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	pb "github.com/meta-node-blockchain/meta-node/pkg/proto"
+	"github.com/meta-node-blockchain/meta-node/types"
+)
+
+// TxResult là kết quả transaction đã được chain chạy xong với receipt RETURNED
+type TxResult struct {
+	Method  string                 `json:"method"`
+	TxHash  common.Hash            `json:"txHash"`
+	GasUsed uint64                 `json:"gasUsed"`
+	Return  string                 `json:"return,omitempty"`
+	Output  map[string]interface{} `json:"output,omitempty"`
+}
+
+// TxError là transaction có receipt khác RETURNED; Reason là lý do revert đã decode
+// (require message, Panic hoặc custom error của ABI như OwnableUnauthorizedAccount)
+type TxError struct {
+	Method  string
+	TxHash  common.Hash
+	Status  pb.RECEIPT_STATUS
+	GasUsed uint64
+	Reason  string
+	Data    []byte
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf("%s reverted (status %v, tx %s): %s", e.Method, e.Status, e.TxHash.Hex(), e.Reason)
+}
+
+// newTxResult chuyển receipt thành TxResult, hoặc TxError nếu transaction không RETURNED
+func newTxResult(method string, receipt types.Receipt, cardAbi *abi.ABI) (*TxResult, error) {
+	if receipt.Status() != pb.RECEIPT_STATUS_RETURNED {
+		return nil, &TxError{
+			Method:  method,
+			TxHash:  receipt.TransactionHash(),
+			Status:  receipt.Status(),
+			GasUsed: receipt.GasUsed(),
+			Reason:  DecodeRevert(cardAbi, receipt.Return()),
+			Data:    receipt.Return(),
+		}
+	}
+	result := &TxResult{
+		Method:  method,
+		TxHash:  receipt.TransactionHash(),
+		GasUsed: receipt.GasUsed(),
+	}
+	if len(receipt.Return()) > 0 {
+		result.Return = hex.EncodeToString(receipt.Return())
+	}
+	return result, nil
+}
+
+// DecodeRevert đọc lý do revert từ dữ liệu trả về: Error(string), Panic(uint256) hoặc
+// custom error khai báo trong ABI; không nhận ra thì trả về dạng hex
+func DecodeRevert(cardAbi *abi.ABI, data []byte) string {
+	if len(data) == 0 {
+		return "no revert data"
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+	if len(data) >= 4 && cardAbi != nil {
+		var selector [4]byte
+		copy(selector[:], data[:4])
+		if abiErr, err := cardAbi.ErrorByID(selector); err == nil {
+			values, err := abiErr.Inputs.Unpack(data[4:])
+			if err == nil {
+				args := make([]string, len(values))
+				for i, v := range values {
+					args[i] = fmt.Sprint(v)
+				}
+				return abiErr.Name + "(" + strings.Join(args, ", ") + ")"
+			}
+			return abiErr.Name
+		}
+	}
+	return "0x" + hex.EncodeToString(data)
+}
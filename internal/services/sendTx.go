@@ -1,147 +1,207 @@
 package services
 
 import (
-	"encoding/hex"
 	"fmt"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	e_common "github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/config"
 	"github.com/meta-node-blockchain/cardvisa/internal/model"
-	"github.com/meta-node-blockchain/meta-node/cmd/client"
 	"github.com/meta-node-blockchain/meta-node/pkg/logger"
-	pb "github.com/meta-node-blockchain/meta-node/pkg/proto"
-	"github.com/meta-node-blockchain/meta-node/pkg/transaction"
+	"github.com/meta-node-blockchain/meta-node/types"
 	"math/big"
-	"time"
 )
 
-type SendTransactionService interface {
+// TokenService cấp token cho yêu cầu requestToken đã giải mã
+type TokenService interface {
 	SubmitToken(
 		user common.Address,
 		tokenid [32]byte,
 		region string,
 		requestId [32]byte,
 		cardHash [32]byte,
-	) (interface{}, error)
-	CallVerifyPublicKey() (interface{}, error)
+	) (*TxResult, error)
+}
+
+// TxStatusService ghi trạng thái giao dịch charge lên contract
+type TxStatusService interface {
 	UpdateTxStatus(
 		tokenid [32]byte,
 		txID string,
 		status uint8,
 		atTime uint64,
 		reason string,
-	) (interface{}, error)
-	GetTx(
-		txID string,
-	) (interface{}, error)
+	) (*TxResult, error)
+	UpdateTxStatusBatch(updates []TxStatusUpdate) (*TxResult, []bool, error)
+}
+
+// SettlementService mint UTXO cho merchant khi giao dịch thành công
+type SettlementService interface {
 	MintUTXO(
 		parentValue *big.Int,
 		ownerPool common.Address,
 		txID string,
-	) (interface{}, error)
-	GetPoolInfo(
-		txID string,
-	) (interface{}, error)
-	sendTransactionAndGetResult(
+	) (*TxResult, error)
+}
+
+// AdminService gồm các lệnh quản trị card contract
+type AdminService interface {
+	SetCardLocked(
+		cardHash [32]byte,
+		locked bool,
+	) (*TxResult, error)
+	SetTokenActive(
+		tokenid [32]byte,
+		active bool,
+	) (*TxResult, error)
+	SetMerchantRule(rule model.MerchantRule) (*TxResult, error)
+	SetGlobalRule(rule model.GlobalRule) (*TxResult, error)
+	SetLock(locked bool) (*TxResult, error)
+	SetAdmin(admin common.Address, ok bool) (*TxResult, error)
+	SetProcessor(processor common.Address) (*TxResult, error)
+	SetBackendPubKey(pubKey []byte) (*TxResult, error)
+	CleanUsage(beforeTimestamp uint64) (*TxResult, error)
+}
+
+// Transactor gửi calldata đã pack tới card contract và trả về receipt thô
+type Transactor interface {
+	SendTransaction(
 		methodName string,
 		input []byte,
-		unpackTo string,
 		attempts int,
-	) (interface{}, error) 
+	) (types.Receipt, error)
+	QueueStats() TxQueueStats
+}
+
+// SendTransactionService gộp mọi thao tác ghi lên card contract của một deployment;
+// package services/fake có bản giả ghi lại lời gọi để test CardHandler
+type SendTransactionService interface {
+	TokenService
+	TxStatusService
+	SettlementService
+	AdminService
+	Transactor
 }
 type sendTransactionService struct {
-	chainClient *client.Client
+	txManager   *TxManager
 	cardAbi     *abi.ABI
 	cardAddress e_common.Address
-	fromAddress e_common.Address
+	policies    config.TransactionsConfig
+	estimator   GasEstimator
 }
 
+// NewSendTransactionService gửi transaction qua txManager; các deployment dùng chung sender
+// phải dùng chung một TxManager để transaction được tuần tự hoá. estimator có thể nil,
+// khi đó policy EstimateGas bị bỏ qua và dùng MaxGas.
 func NewSendTransactionService(
-	chainClient *client.Client,
+	txManager *TxManager,
 	cardAbi *abi.ABI,
 	cardAddress e_common.Address,
-	fromAddress e_common.Address,
+	policies config.TransactionsConfig,
+	estimator GasEstimator,
 ) SendTransactionService {
 	return &sendTransactionService{
-		chainClient: chainClient,
+		txManager:   txManager,
 		cardAbi:     cardAbi,
 		cardAddress: cardAddress,
-		fromAddress: fromAddress,
+		policies:    policies,
+		estimator:   estimator,
 	}
 }
-// General transaction handler with retry, unpack, and timeout logic
+// General transaction handler with retry, unpack, and timeout logic. Receipt khác RETURNED
+// trả về *TxError kèm lý do revert đã decode.
 func (h *sendTransactionService) sendTransactionAndGetResult(
 	methodName string,
 	input []byte,
 	unpackTo string,
 	attempts int,
-) (interface{}, error) {
-	callData := transaction.NewCallData(input)
+) (*TxResult, error) {
+	receipt, err := h.SendTransaction(methodName, input, attempts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newTxResult(methodName, receipt, h.cardAbi)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Transaction %s failed", methodName), err)
+		return nil, err
+	}
+	if unpackTo != "" && len(receipt.Return()) > 0 {
+		kq := make(map[string]interface{})
+		err := h.cardAbi.UnpackIntoMap(kq, unpackTo, receipt.Return())
+		if err != nil {
+			logger.Error(fmt.Sprintf("UnpackIntoMap error for %s", methodName), err)
+			return nil, err
+		}
+		result.Output = kq
+	}
+	return result, nil
+}
 
-	bData, err := callData.Marshal()
+// SendTransaction gửi transaction tới card contract qua TxManager của sender và chờ receipt
+func (h *sendTransactionService) SendTransaction(
+	methodName string,
+	input []byte,
+	attempts int,
+) (types.Receipt, error) {
+	policy := h.policy(input, attempts)
+	receipt, err := h.txManager.Submit(TxRequest{
+		Method:      methodName,
+		To:          h.cardAddress,
+		Data:        input,
+		MaxGas:      h.gasLimit(methodName, input, policy),
+		MaxGasPrice: policy.MaxGasPrice,
+		TimeUse:     policy.TimeUse,
+		Attempts:    policy.Attempts,
+		Timeout:     policy.Timeout,
+	})
 	if err != nil {
-		logger.Error(fmt.Sprintf("Marshal calldata for %s failed", methodName), err)
 		return nil, err
 	}
+	fmt.Printf("rc %s: %v\n", methodName, receipt)
+	return receipt, nil
+}
 
-	relatedAddress := []e_common.Address{}
-	maxGas := uint64(5_000_000)
-	maxGasPrice := uint64(1_000_000_000)
-	timeUse := uint64(0)
-
-	for attempt := 1; attempt <= attempts; attempt++ {
-		ch := make(chan model.ResultData, 1)
-		go func() {
-			receipt, err := h.chainClient.SendTransactionWithDeviceKey(
-				h.fromAddress,
-				h.cardAddress,
-				big.NewInt(0),
-				bData,
-				relatedAddress,
-				maxGas,
-				maxGasPrice,
-				timeUse,
-			)
-			ch <- model.ResultData{
-				Receipt: receipt,
-				Err:     err,
-			}
-		}()
-
-		select {
-		case res := <-ch:
-			if res.Err != nil {
-				logger.Error(fmt.Sprintf("SendTransactionWithDeviceKey error in %s", methodName), res.Err)
-				return nil, res.Err
-			}
-
-			fmt.Printf("rc %s: %v\n", methodName, res.Receipt)
-
-			if res.Receipt.Status() == pb.RECEIPT_STATUS_RETURNED {
-				if unpackTo != "" {
-					kq := make(map[string]interface{})
-					err := h.cardAbi.UnpackIntoMap(kq, unpackTo, res.Receipt.Return())
-					if err != nil {
-						logger.Error(fmt.Sprintf("UnpackIntoMap error for %s", methodName), err)
-						return nil, err
-					}
-					return kq, nil
-				}
-				return true, nil
-			}
-			return hex.EncodeToString(res.Receipt.Return()), nil
-
-		case <-time.After(60 * time.Second):
-			logger.Error(fmt.Sprintf("Timeout in %s", methodName))
-			if attempt < attempts {
-				time.Sleep(1 * time.Second)
-				continue
-			}
-			return nil, fmt.Errorf("timeout after %d attempts in %s", attempts, methodName)
+// policy lấy TxPolicy theo tên method trong ABI (từ selector của input); attempts là
+// số lần chờ mặc định của method khi config không khai báo
+func (h *sendTransactionService) policy(input []byte, attempts int) config.TxPolicy {
+	method := ""
+	if len(input) >= 4 {
+		if m, err := h.cardAbi.MethodById(input[:4]); err == nil {
+			method = m.Name
 		}
 	}
+	policy := h.policies.Policy(method)
+	if policy.Attempts <= 0 {
+		policy.Attempts = attempts
+	}
+	return policy
+}
+
+// gasLimit ước lượng gas nếu policy bật EstimateGas, không vượt MaxGas; ước lượng lỗi
+// (kể cả revert khi mô phỏng) thì gửi với MaxGas để receipt trả lý do revert như thường
+func (h *sendTransactionService) gasLimit(methodName string, input []byte, policy config.TxPolicy) uint64 {
+	if !policy.EstimateGas || h.estimator == nil {
+		return policy.MaxGas
+	}
+	estimate, err := h.estimator.EstimateGas(h.txManager.Sender(), h.cardAddress, input)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("EstimateGas %s failed, dùng MaxGas:", methodName), err)
+		return policy.MaxGas
+	}
+	multiplier := h.policies.GasMultiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	gas := uint64(float64(estimate) * multiplier)
+	if policy.MaxGas > 0 && gas > policy.MaxGas {
+		gas = policy.MaxGas
+	}
+	return gas
+}
 
-	return nil, fmt.Errorf("unexpected error in %s", methodName)
+// QueueStats trả về trạng thái hàng đợi transaction của sender
+func (h *sendTransactionService) QueueStats() TxQueueStats {
+	return h.txManager.Stats()
 }
 
 // SubmitToken calls submitToken method of smart contract
@@ -151,7 +211,7 @@ func (h *sendTransactionService) SubmitToken(
 	region string,
 	requestId [32]byte,
 	cardHash [32]byte,
-) (interface{}, error) {
+) (*TxResult, error) {
 	fmt.Println("SubmitToken")
 	input, err := h.cardAbi.Pack(
 		"submitToken",
@@ -176,7 +236,7 @@ func (h *sendTransactionService) UpdateTxStatus(
 	status uint8,
 	atTime uint64,
 	reason string,
-) (interface{}, error) {
+) (*TxResult, error) {
 	fmt.Println("UpdateTxStatus")
 	input, err := h.cardAbi.Pack(
 		"UpdateTxStatus",
@@ -194,70 +254,166 @@ func (h *sendTransactionService) UpdateTxStatus(
 	return h.sendTransactionAndGetResult("UpdateTxStatus", input, "", 1)
 }
 
-// GetTx calls getTx method of smart contract and unpacks response
-func (h *sendTransactionService) GetTx(
-	txID string,
-) (interface{}, error) {
-	fmt.Println("GetTx")
-	input, err := h.cardAbi.Pack("getTx", txID)
+// UpdateTxStatusBatch calls batchUpdateTxStatus method of smart contract, trả về ok theo
+// từng phần tử (false là phần tử bị contract bỏ qua)
+func (h *sendTransactionService) UpdateTxStatusBatch(updates []TxStatusUpdate) (*TxResult, []bool, error) {
+	input, err := h.cardAbi.Pack("batchUpdateTxStatus", updates)
 	if err != nil {
-		logger.Error("Pack error in GetTx", err)
-		return nil, err
+		logger.Error("Pack error in UpdateTxStatusBatch", err)
+		return nil, nil, err
 	}
 
-	return h.sendTransactionAndGetResult("getTx", input, "getTx", 1)
+	result, err := h.sendTransactionAndGetResult("batchUpdateTxStatus", input, "batchUpdateTxStatus", 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, _ := result.Output["ok"].([]bool)
+	if len(ok) != len(updates) {
+		return result, nil, fmt.Errorf("batchUpdateTxStatus returned %d outcomes for %d updates", len(ok), len(updates))
+	}
+	return result, ok, nil
 }
 
-// CallVerifyPublicKey calls getBackendPubKey and returns only the value
-func (h *sendTransactionService) CallVerifyPublicKey() (interface{}, error) {
-	fmt.Println("getBackendPubKey")
-	input, err := h.cardAbi.Pack("getBackendPubKey")
+func (h *sendTransactionService) MintUTXO(
+	parentValue *big.Int,
+	ownerPool common.Address,
+	txID string,
+) (*TxResult, error) {
+	fmt.Println("MintUTXO")
+	input, err := h.cardAbi.Pack(
+		"MintUTXO",
+		parentValue,
+		ownerPool,
+		txID,
+	)
 	if err != nil {
-		logger.Error("Pack error in getBackendPubKey", err)
+		logger.Error("error when pack call data MintUTXO", err)
 		return nil, err
 	}
+	return h.sendTransactionAndGetResult("MintUTXO", input, "MintUTXO", 1)
 
-	result, err := h.sendTransactionAndGetResult("getBackendPubKey", input, "getBackendPubKey", 1)
+}
+// SetCardLocked calls setCardLocked method of smart contract
+func (h *sendTransactionService) SetCardLocked(
+	cardHash [32]byte,
+	locked bool,
+) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setCardLocked", cardHash, locked)
 	if err != nil {
+		logger.Error("Pack error in SetCardLocked", err)
 		return nil, err
 	}
 
-	if m, ok := result.(map[string]interface{}); ok {
-		return m[""], nil
+	return h.sendTransactionAndGetResult("setCardLocked", input, "", 3)
+}
+
+// SetTokenActive calls setTokenActive method of smart contract
+func (h *sendTransactionService) SetTokenActive(
+	tokenid [32]byte,
+	active bool,
+) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setTokenActive", tokenid, active)
+	if err != nil {
+		logger.Error("Pack error in SetTokenActive", err)
+		return nil, err
 	}
 
-	return result, nil
+	return h.sendTransactionAndGetResult("setTokenActive", input, "", 3)
 }
-// GetPoolInfo calls getPoolInfo method of smart contract and unpacks response
-func (h *sendTransactionService) GetPoolInfo(
-	txID string,
-) (interface{}, error) {
-	input, err := h.cardAbi.Pack("getPoolInfo", txID)
+// SetMerchantRule calls setMerchantRule method of smart contract
+func (h *sendTransactionService) SetMerchantRule(rule model.MerchantRule) (*TxResult, error) {
+	regions := rule.AllowedRegions
+	if regions == nil {
+		regions = []string{}
+	}
+	input, err := h.cardAbi.Pack(
+		"setMerchantRule",
+		regions,
+		new(big.Int).SetUint64(rule.MaxPerMinute),
+		new(big.Int).SetUint64(rule.MaxPerHour),
+		new(big.Int).SetUint64(rule.MaxPerDay),
+		new(big.Int).SetUint64(rule.MaxPerWeek),
+		common.HexToAddress(rule.Merchant),
+	)
 	if err != nil {
-		logger.Error("Pack error in GetPoolInfo", err)
+		logger.Error("Pack error in SetMerchantRule", err)
 		return nil, err
 	}
 
-	return h.sendTransactionAndGetResult("getPoolInfo", input, "getPoolInfo", 1)
+	return h.sendTransactionAndGetResult("setMerchantRule", input, "", 1)
 }
-func (h *sendTransactionService) MintUTXO(
-	parentValue *big.Int,
-	ownerPool common.Address,
-	txID string,
-) (interface{}, error) {
-	fmt.Println("MintUTXO")
+
+// SetGlobalRule calls setGlobalRule method of smart contract
+func (h *sendTransactionService) SetGlobalRule(rule model.GlobalRule) (*TxResult, error) {
 	input, err := h.cardAbi.Pack(
-		"MintUTXO",
-		parentValue,
-		ownerPool,
-		txID,
+		"setGlobalRule",
+		new(big.Int).SetUint64(rule.MaxPerMinute),
+		new(big.Int).SetUint64(rule.MaxPerHour),
+		new(big.Int).SetUint64(rule.MaxPerDay),
+		new(big.Int).SetUint64(rule.MaxPerWeek),
+		new(big.Int).SetUint64(rule.MaxTotal),
 	)
 	if err != nil {
-		logger.Error("error when pack call data MintUTXO", err)
+		logger.Error("Pack error in SetGlobalRule", err)
+		return nil, err
+	}
+
+	return h.sendTransactionAndGetResult("setGlobalRule", input, "", 1)
+}
+
+// SetLock calls setLock method of smart contract
+func (h *sendTransactionService) SetLock(locked bool) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setLock", locked)
+	if err != nil {
+		logger.Error("Pack error in SetLock", err)
+		return nil, err
+	}
+
+	return h.sendTransactionAndGetResult("setLock", input, "", 1)
+}
+
+// SetAdmin calls setAdmin method of smart contract
+func (h *sendTransactionService) SetAdmin(admin common.Address, ok bool) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setAdmin", admin, ok)
+	if err != nil {
+		logger.Error("Pack error in SetAdmin", err)
+		return nil, err
+	}
+
+	return h.sendTransactionAndGetResult("setAdmin", input, "", 1)
+}
+
+// SetProcessor calls setProcessor method of smart contract
+func (h *sendTransactionService) SetProcessor(processor common.Address) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setProcessor", processor)
+	if err != nil {
+		logger.Error("Pack error in SetProcessor", err)
+		return nil, err
+	}
+
+	return h.sendTransactionAndGetResult("setProcessor", input, "", 1)
+}
+
+// SetBackendPubKey calls setBackendPubKey method of smart contract
+func (h *sendTransactionService) SetBackendPubKey(pubKey []byte) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("setBackendPubKey", pubKey)
+	if err != nil {
+		logger.Error("Pack error in SetBackendPubKey", err)
+		return nil, err
+	}
+
+	return h.sendTransactionAndGetResult("setBackendPubKey", input, "", 1)
+}
+
+// CleanUsage calls cleanUsage method of smart contract
+func (h *sendTransactionService) CleanUsage(beforeTimestamp uint64) (*TxResult, error) {
+	input, err := h.cardAbi.Pack("cleanUsage", new(big.Int).SetUint64(beforeTimestamp))
+	if err != nil {
+		logger.Error("Pack error in CleanUsage", err)
 		return nil, err
 	}
-	return h.sendTransactionAndGetResult("MintUTXO", input, "MintUTXO", 1)
 
+	return h.sendTransactionAndGetResult("cleanUsage", input, "", 1)
 }
 // func (h *sendTransactionService) GetPoolInfo(
 // 	txID string,
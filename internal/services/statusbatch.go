@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+)
+
+const statusBatchQueueSize = 1000
+
+// TxStatusUpdate là một phần tử của batchUpdateTxStatus; tên field khớp tuple TxStatusUpdate trong ABI
+type TxStatusUpdate struct {
+	TokenId [32]byte
+	TxID    string
+	Status  uint8
+	AtTime  uint64
+	Reason  string
+}
+
+// TxStatusRejectedError là phần tử bị contract bỏ qua trong một batch đã lên chain
+type TxStatusRejectedError struct {
+	TxID   string
+	Index  int
+	TxHash string
+}
+
+func (e *TxStatusRejectedError) Error() string {
+	return fmt.Sprintf("UpdateTxStatus %s rejected at index %d of batch tx %s", e.TxID, e.Index, e.TxHash)
+}
+
+type statusBatchItem struct {
+	update TxStatusUpdate
+	done   chan statusBatchOutcome
+}
+
+type statusBatchOutcome struct {
+	result *TxResult
+	err    error
+}
+
+// statusBatcher gộp các lời gọi UpdateTxStatus trong một cửa sổ ngắn thành một transaction
+// batchUpdateTxStatus. Một goroutine duy nhất gom và gửi lần lượt từng batch, phần tử giữ
+// đúng thứ tự gọi nên các cập nhật của cùng một txID không bị đảo. Mỗi caller vẫn chờ và
+// nhận kết quả của riêng phần tử mình.
+type statusBatcher struct {
+	SendTransactionService
+	window  time.Duration
+	maxSize int
+	queue   chan *statusBatchItem
+}
+
+// ProbeStatusBatch gọi thử batchUpdateTxStatus với mảng rỗng bằng eth_call từ địa chỉ sender
+// để biết contract đã deploy có method này không. ABI có method chưa chắc bytecode trên chain
+// có: contract cũ không có hàm này (và không có fallback) sẽ revert, địa chỉ không có code
+// trả về dữ liệu rỗng; cả hai đều là false, chỉ lỗi RPC mới trả err.
+func ProbeStatusBatch(
+	rpcURL string,
+	cardAbi *abi.ABI,
+	cardAddress common.Address,
+	fromAddress common.Address,
+) (bool, error) {
+	if _, ok := cardAbi.Methods["batchUpdateTxStatus"]; !ok {
+		return false, nil
+	}
+	input, err := cardAbi.Pack("batchUpdateTxStatus", []TxStatusUpdate{})
+	if err != nil {
+		return false, err
+	}
+	rpc, err := ethclient.Dial(rpcURL)
+	if err != nil {
+		return false, err
+	}
+	defer rpc.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	data, err := rpc.CallContract(ctx, ethereum.CallMsg{
+		From: fromAddress,
+		To:   &cardAddress,
+		Data: input,
+	}, nil)
+	if err != nil {
+		var dataErr gethrpc.DataError
+		if errors.As(err, &dataErr) || strings.Contains(err.Error(), "revert") {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := cardAbi.Unpack("batchUpdateTxStatus", data); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// NewStatusBatcher bọc service để UpdateTxStatus đi qua batch; ABI không có
+// batchUpdateTxStatus (contract cũ) thì trả lại service gốc. Caller phải kiểm tra contract
+// đã deploy có method (ProbeStatusBatch hoặc CardContractConfig.StatusBatch) trước khi bọc.
+func NewStatusBatcher(
+	service SendTransactionService,
+	cardAbi *abi.ABI,
+	window time.Duration,
+	maxSize int,
+) SendTransactionService {
+	if _, ok := cardAbi.Methods["batchUpdateTxStatus"]; !ok {
+		logger.Warn("ABI không có batchUpdateTxStatus, tắt StatusBatch")
+		return service
+	}
+	if maxSize <= 1 {
+		maxSize = 50
+	}
+	b := &statusBatcher{
+		SendTransactionService: service,
+		window:                 window,
+		maxSize:                maxSize,
+		queue:                  make(chan *statusBatchItem, statusBatchQueueSize),
+	}
+	go b.run()
+	return b
+}
+
+// UpdateTxStatus đưa cập nhật vào batch kế tiếp và chờ kết quả; TxResult dùng chung
+// cho cả batch (cùng TxHash)
+func (b *statusBatcher) UpdateTxStatus(
+	tokenid [32]byte,
+	txID string,
+	status uint8,
+	atTime uint64,
+	reason string,
+) (*TxResult, error) {
+	item := &statusBatchItem{
+		update: TxStatusUpdate{
+			TokenId: tokenid,
+			TxID:    txID,
+			Status:  status,
+			AtTime:  atTime,
+			Reason:  reason,
+		},
+		done: make(chan statusBatchOutcome, 1),
+	}
+	b.queue <- item
+	out := <-item.done
+	return out.result, out.err
+}
+
+func (b *statusBatcher) run() {
+	for first := range b.queue {
+		batch := []*statusBatchItem{first}
+		timer := time.NewTimer(b.window)
+	collect:
+		for len(batch) < b.maxSize {
+			select {
+			case item := <-b.queue:
+				batch = append(batch, item)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		b.flush(batch)
+	}
+}
+
+func (b *statusBatcher) flush(batch []*statusBatchItem) {
+	// Một phần tử thì gửi UpdateTxStatus thường, không cần trả thêm chi phí tuple
+	if len(batch) == 1 {
+		u := batch[0].update
+		result, err := b.SendTransactionService.UpdateTxStatus(u.TokenId, u.TxID, u.Status, u.AtTime, u.Reason)
+		batch[0].done <- statusBatchOutcome{result: result, err: err}
+		return
+	}
+
+	updates := make([]TxStatusUpdate, len(batch))
+	for i, item := range batch {
+		updates[i] = item.update
+	}
+	result, ok, err := b.SendTransactionService.UpdateTxStatusBatch(updates)
+	if err != nil {
+		logger.Error(fmt.Sprintf("batchUpdateTxStatus %d phần tử lỗi:", len(batch)), err)
+		for _, item := range batch {
+			item.done <- statusBatchOutcome{err: err}
+		}
+		return
+	}
+	logger.Info(fmt.Sprintf("batchUpdateTxStatus %d phần tử, tx %s", len(batch), result.TxHash.Hex()))
+	for i, item := range batch {
+		if !ok[i] {
+			item.done <- statusBatchOutcome{
+				result: result,
+				err: &TxStatusRejectedError{
+					TxID:   item.update.TxID,
+					Index:  i,
+					TxHash: result.TxHash.Hex(),
+				},
+			}
+			continue
+		}
+		item.done <- statusBatchOutcome{result: result}
+	}
+}
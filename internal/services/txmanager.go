@@ -0,0 +1,311 @@
+package services
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+	"github.com/meta-node-blockchain/meta-node/pkg/logger"
+	"github.com/meta-node-blockchain/meta-node/types"
+)
+
+const (
+	defaultTxTimeout = 60 * time.Second
+	txQueueSize      = 1000
+	recentTxLimit    = 50
+)
+
+// ErrTxUnconfirmed là transaction đã gửi nhưng chưa có receipt sau mọi lần chờ. Transaction
+// có thể vẫn lên chain nên không được gửi lại mù; caller phải kiểm tra state trước khi retry.
+var ErrTxUnconfirmed = errors.New("transaction submitted but receipt not received")
+
+// TxRequest là một transaction chờ gửi qua TxManager
+type TxRequest struct {
+	Method      string
+	To          common.Address
+	Data        []byte // ABI input thô, ChainBackend tự đóng gói theo định dạng của chain
+	MaxGas      uint64
+	MaxGasPrice uint64
+	TimeUse     uint64
+	// Số cửa sổ Timeout chờ receipt của cùng một lần gửi
+	Attempts int
+	Timeout  time.Duration
+
+	// submitted được backend gọi ngay khi transaction đã broadcast, trước khi có receipt
+	submitted func(hash common.Hash)
+}
+
+// ReceiptReader là backend tra được receipt theo tx hash. TxManager dùng để kiểm tra transaction
+// trước đó đã vào block chưa trước khi gửi lại cùng payload; trả nil, nil khi chưa có receipt.
+type ReceiptReader interface {
+	Receipt(req TxRequest, hash common.Hash) (types.Receipt, error)
+}
+
+// InFlightTx là transaction đã gửi và đang chờ receipt
+type InFlightTx struct {
+	Method string `json:"method"`
+	To     string `json:"to"`
+	// TxHash có khi backend báo hash lúc broadcast (ethBackend), meta-node chỉ có hash khi có receipt
+	TxHash      string `json:"txHash,omitempty"`
+	SubmittedAt int64  `json:"submittedAt"`
+	// Orphaned: caller đã nhận ErrTxUnconfirmed nhưng lời gọi tới chain vẫn chưa trả về
+	Orphaned bool `json:"orphaned"`
+}
+
+// SentTx là transaction đã có receipt
+type SentTx struct {
+	Method  string `json:"method"`
+	TxHash  string `json:"txHash"`
+	Status  string `json:"status"`
+	GasUsed uint64 `json:"gasUsed"`
+	At      int64  `json:"at"`
+}
+
+// TxQueueStats là trạng thái hàng đợi transaction của một sender
+type TxQueueStats struct {
+	Sender     string       `json:"sender"`
+	QueueDepth int          `json:"queueDepth"`
+	InFlight   []InFlightTx `json:"inFlight"`
+	Recent     []SentTx     `json:"recent"`
+	// StalledSince là lúc gửi transaction orphaned cũ nhất mà worker vẫn đang chờ; khác 0 nghĩa là
+	// mọi transaction khác của sender đang bị giữ lại sau nó
+	StalledSince int64 `json:"stalledSince,omitempty"`
+}
+
+type txJob struct {
+	req  TxRequest
+	done chan model.ResultData
+}
+
+// unconfirmedTx là transaction mà caller đã nhận ErrTxUnconfirmed, giữ lại theo payload để lần
+// gửi lại cùng payload kiểm tra nó trước
+type unconfirmedTx struct {
+	hash    common.Hash
+	receipt types.Receipt
+}
+
+// TxManager tuần tự hoá transaction của một sender: tại mỗi thời điểm chỉ một lời gọi tới chain
+// nên nonce không bị tranh chấp giữa các goroutine. Khi hết thời gian chờ, caller nhận
+// ErrTxUnconfirmed nhưng worker vẫn giữ lượt tới khi lời gọi đó kết thúc; caller gửi lại cùng
+// payload sẽ nhận receipt của transaction cũ nếu nó đã vào block thay vì gửi transaction mới.
+type TxManager struct {
+	backend ChainBackend
+	queue   chan *txJob
+
+	mu          sync.Mutex
+	nextID      uint64
+	inFlight    map[uint64]*InFlightTx
+	recent      []SentTx
+	unconfirmed map[string]*unconfirmedTx
+}
+
+// NewTxManager khởi tạo và chạy worker gửi transaction qua backend cho sender của backend
+func NewTxManager(backend ChainBackend) *TxManager {
+	m := &TxManager{
+		backend:     backend,
+		queue:       make(chan *txJob, txQueueSize),
+		inFlight:    make(map[uint64]*InFlightTx),
+		unconfirmed: make(map[string]*unconfirmedTx),
+	}
+	go m.run()
+	return m
+}
+
+// Submit xếp transaction vào hàng đợi và chờ receipt
+func (m *TxManager) Submit(req TxRequest) (types.Receipt, error) {
+	job := &txJob{req: req, done: make(chan model.ResultData, 1)}
+	m.queue <- job
+	res := <-job.done
+	return res.Receipt, res.Err
+}
+
+// Sender là địa chỉ ký mọi transaction của TxManager
+func (m *TxManager) Sender() common.Address {
+	return m.backend.Sender()
+}
+
+// Stats trả về độ sâu hàng đợi, transaction đang chờ và các transaction gần nhất
+func (m *TxManager) Stats() TxQueueStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := TxQueueStats{
+		Sender:     m.backend.Sender().Hex(),
+		QueueDepth: len(m.queue),
+		InFlight:   make([]InFlightTx, 0, len(m.inFlight)),
+		Recent:     append([]SentTx{}, m.recent...),
+	}
+	for _, tx := range m.inFlight {
+		stats.InFlight = append(stats.InFlight, *tx)
+		if tx.Orphaned && (stats.StalledSince == 0 || tx.SubmittedAt < stats.StalledSince) {
+			stats.StalledSince = tx.SubmittedAt
+		}
+	}
+	return stats
+}
+
+func (m *TxManager) run() {
+	for job := range m.queue {
+		m.process(job)
+	}
+}
+
+func (m *TxManager) process(job *txJob) {
+	req := job.req
+	key := payloadKey(req)
+	if receipt, ok, err := m.checkEarlier(key, req); ok {
+		job.done <- model.ResultData{Receipt: receipt, Err: err}
+		return
+	}
+
+	attempts := req.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultTxTimeout
+	}
+
+	id := m.track(req)
+	req.submitted = func(hash common.Hash) {
+		m.setHash(id, hash)
+	}
+	ch := make(chan model.ResultData, 1)
+	go func() {
+		receipt, err := m.backend.SendTransaction(req)
+		m.untrack(id, req.Method, receipt)
+		ch <- model.ResultData{Receipt: receipt, Err: err}
+	}()
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		select {
+		case res := <-ch:
+			if res.Err != nil {
+				logger.Error(fmt.Sprintf("SendTransaction error in %s", req.Method), res.Err)
+			}
+			job.done <- res
+			return
+		case <-time.After(timeout):
+			logger.Warn(fmt.Sprintf("Timeout in %s, vẫn chờ receipt (%d/%d)", req.Method, attempt, attempts))
+		}
+	}
+	hash := m.orphan(id)
+	logger.Error(fmt.Sprintf("No receipt for %s after %d attempts", req.Method, attempts))
+	m.mu.Lock()
+	m.unconfirmed[key] = &unconfirmedTx{hash: hash}
+	m.mu.Unlock()
+	if hash != (common.Hash{}) {
+		job.done <- model.ResultData{Err: fmt.Errorf("%s (tx %s): %w", req.Method, hash.Hex(), ErrTxUnconfirmed)}
+	} else {
+		job.done <- model.ResultData{Err: fmt.Errorf("%s: %w", req.Method, ErrTxUnconfirmed)}
+	}
+
+	// Giữ lượt của sender tới khi lời gọi kết thúc để transaction kế tiếp không tranh nonce.
+	// Trong lúc chờ, Stats báo StalledSince để readyz đưa service ra khỏi trạng thái ready.
+	res := <-ch
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if entry, ok := m.unconfirmed[key]; ok && res.Receipt != nil {
+		entry.hash = res.Receipt.TransactionHash()
+		entry.receipt = res.Receipt
+	}
+}
+
+// checkEarlier xử lý payload trùng với transaction trước đó bị ErrTxUnconfirmed: trả receipt của
+// transaction cũ nếu nó đã vào block, trả lỗi nếu không kiểm tra được; ok=false là được gửi mới.
+func (m *TxManager) checkEarlier(key string, req TxRequest) (types.Receipt, bool, error) {
+	m.mu.Lock()
+	earlier, found := m.unconfirmed[key]
+	m.mu.Unlock()
+	if !found {
+		return nil, false, nil
+	}
+	receipt := earlier.receipt
+	if receipt == nil && earlier.hash != (common.Hash{}) {
+		reader, ok := m.backend.(ReceiptReader)
+		if ok {
+			var err error
+			receipt, err = reader.Receipt(req, earlier.hash)
+			if err != nil {
+				return nil, true, fmt.Errorf("check inclusion of %s: %w", earlier.hash.Hex(), err)
+			}
+		}
+	}
+	m.mu.Lock()
+	delete(m.unconfirmed, key)
+	m.mu.Unlock()
+	if receipt == nil {
+		logger.Warn(fmt.Sprintf("%s trước đó không có receipt, gửi lại", req.Method), earlier.hash.Hex())
+		return nil, false, nil
+	}
+	logger.Info(fmt.Sprintf("%s đã vào block, dùng receipt cũ thay vì gửi lại", req.Method), receipt.TransactionHash().Hex())
+	return receipt, true, nil
+}
+
+// payloadKey nhận diện cùng một lời gọi được gửi lại
+func payloadKey(req TxRequest) string {
+	return req.To.Hex() + ":" + hex.EncodeToString(req.Data)
+}
+
+func (m *TxManager) track(req TxRequest) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	m.inFlight[m.nextID] = &InFlightTx{
+		Method:      req.Method,
+		To:          req.To.Hex(),
+		SubmittedAt: time.Now().Unix(),
+	}
+	return m.nextID
+}
+
+func (m *TxManager) setHash(id uint64, hash common.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tx, ok := m.inFlight[id]; ok {
+		tx.TxHash = hash.Hex()
+	}
+}
+
+// orphan đánh dấu transaction đã hết lượt chờ, trả về hash nếu backend đã báo
+func (m *TxManager) orphan(id uint64) common.Hash {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tx, ok := m.inFlight[id]
+	if !ok {
+		return common.Hash{}
+	}
+	tx.Orphaned = true
+	if tx.TxHash == "" {
+		return common.Hash{}
+	}
+	return common.HexToHash(tx.TxHash)
+}
+
+// untrack bỏ transaction khỏi danh sách đang chờ và ghi tx hash vào lịch sử gần nhất,
+// kể cả receipt về muộn của transaction đã bị coi là orphaned
+func (m *TxManager) untrack(id uint64, method string, receipt types.Receipt) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if tx, ok := m.inFlight[id]; ok && tx.Orphaned && receipt != nil {
+		logger.Warn("⚠️ Receipt về muộn cho", method, receipt.TransactionHash().Hex())
+	}
+	delete(m.inFlight, id)
+	if receipt == nil {
+		return
+	}
+	m.recent = append(m.recent, SentTx{
+		Method:  method,
+		TxHash:  receipt.TransactionHash().Hex(),
+		Status:  fmt.Sprint(receipt.Status()),
+		GasUsed: receipt.GasUsed(),
+		At:      time.Now().Unix(),
+	})
+	if len(m.recent) > recentTxLimit {
+		m.recent = m.recent[len(m.recent)-recentTxLimit:]
+	}
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+var (
+	ErrAmountOverflow  = errors.New("amount overflows acquirer minor units")
+	ErrAmountPrecision = errors.New("amount is not representable in currency minor units")
+	ErrAmountNegative  = errors.New("amount must not be negative")
+)
+
+// TokenToMinorUnits đổi số tiền on-chain (đơn vị nhỏ nhất của token, tokenDecimals chữ số thập phân)
+// sang minor unit của currency mà acquirer dùng. Từ chối nếu bị tràn int64 hoặc mất phần lẻ.
+func TokenToMinorUnits(value *big.Int, tokenDecimals int32, currency string, exponent int32) (model.Money, error) {
+	if value == nil {
+		return model.Money{}, fmt.Errorf("amount is nil")
+	}
+	if value.Sign() < 0 {
+		return model.Money{}, ErrAmountNegative
+	}
+	minor := new(big.Int).Set(value)
+	if exponent >= tokenDecimals {
+		minor.Mul(minor, pow10(exponent-tokenDecimals))
+	} else {
+		rem := new(big.Int)
+		minor.QuoRem(minor, pow10(tokenDecimals-exponent), rem)
+		if rem.Sign() != 0 {
+			return model.Money{}, fmt.Errorf("%w: %s with %d token decimals", ErrAmountPrecision, value.String(), tokenDecimals)
+		}
+	}
+	if !minor.IsInt64() {
+		return model.Money{}, fmt.Errorf("%w: %s", ErrAmountOverflow, value.String())
+	}
+	return model.Money{
+		Amount:   minor.Int64(),
+		Currency: currency,
+		Exponent: exponent,
+	}, nil
+}
+
+// MinorUnitsToToken là phép đổi ngược của TokenToMinorUnits.
+func MinorUnitsToToken(m model.Money, tokenDecimals int32) *big.Int {
+	value := big.NewInt(m.Amount)
+	if tokenDecimals >= m.Exponent {
+		return value.Mul(value, pow10(tokenDecimals-m.Exponent))
+	}
+	return value.Quo(value, pow10(m.Exponent-tokenDecimals))
+}
+
+func pow10(n int32) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
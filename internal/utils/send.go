@@ -8,7 +8,6 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"math/big"
 	"net/http"
 	"strings"
 	"time"
@@ -25,8 +24,8 @@ func ValidateCard(card model.CardData) error {
 	}
 	return nil
 }
-func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Address, thirdPartyApiUrl string) (model.TxResponse,error) {
-    var result model.TxResponse
+func SendToThirdParty(card model.CardData, txID string, amount model.Money, merchant model.Merchant, thirdPartyApiUrl string) (model.TxResponse,error) {
+    result := model.TxResponse{TransactionID: txID, Amount: amount}
     if err := ValidateCard(card); err != nil {
         fmt.Println("Validation error:", err)
         return result,err
@@ -34,10 +33,12 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
     // Struct định nghĩa đúng định dạng JSONcard
     type Payload struct {
         MID        string `json:"m_id"`
+        TerminalID string `json:"terminal_id,omitempty"`
         TxID       string `json:"tx_id"`
         CardNumber string `json:"card_number"`
         ExpDate    string `json:"exp_date"`
         Amount     int64  `json:"amount"`
+        Currency   string `json:"currency"`
         WalletTo   string `json:"wallet_to"`
         FeePayer   int    `json:"fee_payer"`
         CVV string `json:"cvv"`
@@ -45,13 +46,15 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
 
     // Tạo payload
     payload := Payload{
-        MID:        "pos123",
-        TxID:       generateTxID(),
+        MID:        merchant.MID,
+        TerminalID: merchant.TerminalID,
+        TxID:       txID,
         CardNumber: card.CardNumber,
         ExpDate:    fmt.Sprintf("%s-%s", card.ExpYear, padLeft(card.ExpMonth, 2, "0")),
-        Amount:     amount.Int64(),
-        WalletTo:   merchant.Hex()[2:], // loại bỏ "0x"
-        FeePayer:   1,
+        Amount:     amount.Amount,
+        Currency:   amount.Currency,
+        WalletTo:   common.HexToAddress(merchant.Address).Hex()[2:], // loại bỏ "0x"
+        FeePayer:   merchant.FeePayer,
         CVV : card.CVV,
     }
 
@@ -95,11 +98,23 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
     log.Println("📨 transaction ID la:", payload.TxID)
     // In thử body để debug
     log.Println("📨 Raw response:", string(body))
+    if challenge := parseChallenge(body); challenge != nil {
+        challenge.TxID = payload.TxID
+        result = model.TxResponse{
+            Message       :string(body),
+            Status        :"challenge required",
+            TransactionID :payload.TxID,
+            Amount        :amount,
+            Challenge     :challenge,
+        }
+        return result,nil
+    }
     if strings.Contains(string(body), "success") {
         result = model.TxResponse{
             Message       :string(body),
             Status        :"success",
             TransactionID :payload.TxID,
+            Amount        :amount,
         }
         return result,nil
     } else if containsAny(string(body), []string{"being processed"}) {
@@ -107,6 +122,7 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
             Message       :string(body),
             Status        :"being processed",
             TransactionID :payload.TxID,
+            Amount        :amount,
         }
         return result,nil    
     }else {
@@ -117,6 +133,7 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
                 Message       :string(body),
                 Status        :"failed",
                 TransactionID :payload.TxID,
+                Amount        :amount,
             }
             return result,nil    
         }
@@ -124,6 +141,59 @@ func SendToThirdParty(card model.CardData, amount *big.Int, merchant common.Addr
     }
     
 }
+// parseChallenge nhận diện phản hồi yêu cầu xác thực chủ thẻ (3-D Secure redirect hoặc OTP)
+func parseChallenge(body []byte) *model.Challenge {
+    var resp struct {
+        RedirectURL   string `json:"redirect_url"`
+        OTPRequired   bool   `json:"otp_required"`
+        ChallengeType string `json:"challenge_type"`
+    }
+    if err := json.Unmarshal(body, &resp); err != nil {
+        return nil
+    }
+    switch {
+    case resp.RedirectURL != "":
+        return &model.Challenge{Type: model.ChallengeTypeRedirect, RedirectURL: resp.RedirectURL}
+    case resp.OTPRequired || resp.ChallengeType == model.ChallengeTypeOTP:
+        return &model.Challenge{Type: model.ChallengeTypeOTP}
+    }
+    return nil
+}
+
+// SubmitChallengeResult gửi OTP của chủ thẻ sang acquirer để hoàn tất giao dịch đang chờ xác thực
+func SubmitChallengeResult(challengeApiUrl string, txID string, mID string, otp string) (model.TxResponse, error) {
+    result := model.TxResponse{TransactionID: txID}
+    data, err := json.Marshal(map[string]string{
+        "tx_id": txID,
+        "m_id":  mID,
+        "otp":   otp,
+    })
+    if err != nil {
+        return result, err
+    }
+    client := &http.Client{Timeout: 30 * time.Second}
+    resp, err := client.Post(challengeApiUrl, "application/json", bytes.NewBuffer(data))
+    if err != nil {
+        return result, err
+    }
+    defer resp.Body.Close()
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return result, err
+    }
+    log.Println("📨 Challenge response:", string(body))
+    result.Message = string(body)
+    switch {
+    case strings.Contains(result.Message, "success"):
+        result.Status = "success"
+    case containsAny(result.Message, []string{"being processed", "pending"}):
+        result.Status = "being processed"
+    default:
+        result.Status = "failed"
+    }
+    return result, nil
+}
+
 // Pad helper
 func padLeft(str string, length int, pad string) string {
     for len(str) < length {
@@ -147,17 +217,21 @@ func padLeft(str string, length int, pad string) string {
 //     return hexPart // tổng 14 ký tự
 
 // }
-func generateTxID() string {
+func GenerateTxID() string {
     b := make([]byte, 11) // 11 bytes = 22 hex digits
     _, _ = rand.Read(b)
     hexPart := hex.EncodeToString(b)
     return hexPart[:14] // đảm bảo chính xác 14 ký tự
 }
-func UpdateStatus(txID string) string {
+func UpdateStatus(txID string, mID string) string {
     url := "https://payment-card.vipn.net/transaction/detail"
-    payload := fmt.Sprintf(`{"tx_id":"%s","m_id":"pos123"}`, txID)
+    payload, err := json.Marshal(map[string]string{"tx_id": txID, "m_id": mID})
+    if err != nil {
+        log.Println("❌ Lỗi tạo payload:", err)
+        return ""
+    }
 
-    req, err := http.NewRequest("POST", url, strings.NewReader(payload))
+    req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
     if err != nil {
         log.Println("❌ Lỗi tạo request:", err)
         return ""
@@ -206,6 +280,23 @@ func UpdateStatus(txID string) string {
     return string(body)
 }
 
+// ParseStatusResponse tách status và message từ phản hồi của UpdateStatus. Phản hồi có thể là
+// object JSON {"status": ..., "message": ...}, chuỗi JSON hoặc text thuần.
+func ParseStatusResponse(body string) (string, string) {
+    var resp struct {
+        Status  string `json:"status"`
+        Message string `json:"message"`
+    }
+    if err := json.Unmarshal([]byte(body), &resp); err == nil {
+        return resp.Status, resp.Message
+    }
+    var status string
+    if err := json.Unmarshal([]byte(body), &status); err == nil {
+        return status, ""
+    }
+    return strings.TrimSpace(body), ""
+}
+
 // func callSmartContractUpdate(txID, status string, atTime int64) {
 //     // Gọi hàm trên smart contract để cập nhật trạng thái: success | failed
 //     log.Printf("📡 Cập nhật trạng thái lên smart contract: %s = %s = %s \n", txID, status,atTime)
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/meta-node-blockchain/cardvisa/internal/model"
+)
+
+// ParseSettlementFile đọc file settlement của acquirer. File .csv cần header
+// tx_id,status,amount[,currency]; các file khác được đọc như mảng JSON.
+func ParseSettlementFile(path string) ([]model.SettlementRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return parseSettlementCSV(f)
+	}
+	var records []model.SettlementRecord
+	if err := json.NewDecoder(f).Decode(&records); err != nil {
+		return nil, fmt.Errorf("parse settlement json: %w", err)
+	}
+	return records, nil
+}
+
+func parseSettlementCSV(r io.Reader) ([]model.SettlementRecord, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read settlement header: %w", err)
+	}
+	col := map[string]int{}
+	for i, name := range header {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"tx_id", "status", "amount"} {
+		if _, ok := col[required]; !ok {
+			return nil, fmt.Errorf("settlement csv missing column %q", required)
+		}
+	}
+	records := []model.SettlementRecord{}
+	for line := 2; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		amount, err := strconv.ParseInt(row[col["amount"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid amount: %w", line, err)
+		}
+		record := model.SettlementRecord{
+			TxID:   row[col["tx_id"]],
+			Status: row[col["status"]],
+			Amount: amount,
+		}
+		if i, ok := col["currency"]; ok && i < len(row) {
+			record.Currency = row[i]
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"log"
+	"strings"
 )
 
 func GenerateTokenID() [32]byte {
@@ -18,4 +19,11 @@ func GenerateTokenID() [32]byte {
 	hash := sha256.Sum256(randomBytes)
 
 	return hash
-}
\ No newline at end of file
+}
+// MaskCardNumber chỉ giữ lại 4 số cuối của số thẻ
+func MaskCardNumber(cardNumber string) string {
+	if len(cardNumber) <= 4 {
+		return strings.Repeat("*", len(cardNumber))
+	}
+	return strings.Repeat("*", len(cardNumber)-4) + cardNumber[len(cardNumber)-4:]
+}